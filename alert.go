@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Интервалы напоминаний о выпавшей мастерноде (секция [monitor] INI файла)
+var (
+	RemindStart int64 = 600   // Первое напоминание через, сек. (дальше интервал удваивается)
+	RemindMax   int64 = 21600 // Максимальный интервал напоминаний, сек. (0 - без напоминаний)
+)
+
+// Через сколько сек. без свежих данных о валидаторах оповещать пользователей о сбое мониторинга
+var StaleAfter int64 = 300
+
+// Пользователи оповещены о сбое мониторинга (только для горутины monitor)
+var degradedAlerted bool
+
+// Время запуска бота: до первого получения валидаторов устаревание считается от него
+var startedAt = time.Now()
+
+// Состояние мастерноды пользователя для оповещений
+type nodeAlert struct {
+	Down      bool          // мастернода не в валидаторах
+	DownSince time.Time     // когда выпала
+	Alerted   bool          // пользователь оповещён о выпадении
+	NextAlert time.Time     // время следующего напоминания
+	Interval  time.Duration // текущий интервал напоминаний
+	Seen      bool          // мастернода проверялась в этом цикле
+
+	MissedLevel   int  // последний порог пропуска блоков, о котором оповещён пользователь
+	RankAlerted   bool // пользователь оповещён, что место по стэку ниже порога
+	MarginAlerted bool // пользователь оповещён, что запас стэка меньше порога
+}
+
+// Состояния мастернод пользователей, ключ - chatID:pubkey (только для горутины monitor)
+var nodeAlerts = map[string]*nodeAlert{}
+
+func alertKey(chatID int64, pubKey string) string {
+	return fmt.Sprintf("%d:%s", chatID, pubKey)
+}
+
+// Проверка смены состояния мастерноды, возвращает текст оповещения ("" - оповещать не нужно)
+func checkNodeAlert(chatID int64, lang string, oneNode nodeData, inValid bool, now time.Time) string {
+	key := alertKey(chatID, oneNode.PubKey)
+	alrt, ok := nodeAlerts[key]
+	if !ok {
+		alrt = &nodeAlert{}
+		nodeAlerts[key] = alrt
+	}
+	alrt.Seen = true
+
+	// мастернода вернулась в валидаторы
+	if inValid {
+		retTxt := ""
+		if alrt.Down && alrt.Alerted && oneNode.Notification {
+			retTxt = tr(lang, "alert_back", oneNode.Label, now.Sub(alrt.DownSince).Truncate(time.Second))
+		}
+		*alrt = nodeAlert{Seen: true, MissedLevel: alrt.MissedLevel, RankAlerted: alrt.RankAlerted, MarginAlerted: alrt.MarginAlerted}
+		return retTxt
+	}
+
+	// мастернода только что выпала
+	if !alrt.Down {
+		alrt.Down = true
+		alrt.DownSince = now
+		alrt.Alerted = false
+	}
+	if !oneNode.Notification {
+		return ""
+	}
+
+	if !alrt.Alerted {
+		alrt.Alerted = true
+		alrt.Interval = time.Duration(RemindStart) * time.Second
+		alrt.NextAlert = now.Add(alrt.Interval)
+		return tr(lang, "alert_down", oneNode.Label)
+	}
+
+	// напоминание с увеличением интервала
+	if RemindMax > 0 && RemindStart > 0 && !now.Before(alrt.NextAlert) {
+		alrt.Interval *= 2
+		if alrt.Interval > time.Duration(RemindMax)*time.Second {
+			alrt.Interval = time.Duration(RemindMax) * time.Second
+		}
+		alrt.NextAlert = now.Add(alrt.Interval)
+		return tr(lang, "alert_still_down", oneNode.Label, now.Sub(alrt.DownSince).Truncate(time.Second))
+	}
+	return ""
+}
+
+// Удаление состояний мастернод, которые больше не отслеживаются
+func cleanNodeAlerts() {
+	for key, alrt := range nodeAlerts {
+		if !alrt.Seen {
+			delete(nodeAlerts, key)
+		} else {
+			alrt.Seen = false
+		}
+	}
+}
+
+// Учёт сбоев получения данных о валидаторах и оповещение пользователей о сбое мониторинга
+func checkDegraded(bot *tgbotapi.BotAPI, dataOk bool, now time.Time) {
+	allCand.SetStale(!dataOk)
+	validUpdated, validStale := allCand.Updated()
+	staleSince := validUpdated
+	if staleSince.IsZero() {
+		staleSince = startedAt
+	}
+	if validStale {
+		mtrStale.Set(1)
+	} else {
+		mtrStale.Set(0)
+	}
+
+	msgKey := ""
+	if dataOk && degradedAlerted {
+		degradedAlerted = false
+		msgKey = "alert_restored"
+	} else if !dataOk && !degradedAlerted && now.Sub(staleSince) > time.Duration(StaleAfter)*time.Second {
+		degradedAlerted = true
+		msgKey = "alert_degraded"
+	}
+	if msgKey == "" {
+		return
+	}
+
+	for _, oneUser := range allUser.Snapshot() {
+		if wantsAlerts(oneUser) {
+			sendMessage(bot, tgbotapi.NewMessage(oneUser.ChatID, tr(userLang(oneUser), msgKey)))
+		}
+	}
+}
+
+// Пользователь получает оповещения: есть своя мастернода с включенными оповещениями
+// или подписка (/watch), у подписок оповещения не отключаются
+func wantsAlerts(usr usrData) bool {
+	for _, oneNode := range usr.Nodes {
+		if oneNode.Notification {
+			return true
+		}
+	}
+	return len(usr.Watch) > 0
+}
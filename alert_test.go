@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestWantsAlerts(t *testing.T) {
+	tests := []struct {
+		name string
+		usr  usrData
+		want bool
+	}{
+		{"no nodes", usrData{ChatID: 1}, false},
+		{"notification on", usrData{ChatID: 1, Nodes: []nodeData{{PubKey: "Mp1", Notification: true}}}, true},
+		{"notification off", usrData{ChatID: 1, Nodes: []nodeData{{PubKey: "Mp1"}}}, false},
+		{"watch only", usrData{ChatID: 1, Watch: []watchData{{PubKey: "Mp2", Alerts: watchAlerts}}}, true},
+		{"notification off with watch", usrData{ChatID: 1, Nodes: []nodeData{{PubKey: "Mp1"}}, Watch: []watchData{{PubKey: "Mp2"}}}, true},
+	}
+	for _, tt := range tests {
+		if got := wantsAlerts(tt.usr); got != tt.want {
+			t.Errorf("%s: wantsAlerts() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+)
+
+// Настройки пропуска блоков (секция [monitor] INI файла)
+var (
+	MissedWindow = 24              // Окно последних блоков для подсчёта пропусков
+	MissedLevels = []int{3, 6, 10} // Пороги оповещения по умолчанию
+)
+
+var (
+	lastBlock    int                   // последний обработанный блок (только для горутины monitor)
+	latestHeight int                   // высота сети по последнему опросу (под missedMutex)
+	missedBlocks = map[string][]bool{} // pubkey -> последние блоки (true - пропущен)
+	missedMutex  sync.RWMutex
+)
+
+// Разбор порогов пропуска блоков из строки вида "3,6,10"
+func parseMissedLevels(str string) ([]int, error) {
+	levels := []int{}
+	for _, oneLvl := range strings.Split(str, ",") {
+		oneLvl = strings.TrimSpace(oneLvl)
+		if oneLvl == "" {
+			continue
+		}
+		lvl, err := strconv.Atoi(oneLvl)
+		if err != nil || lvl <= 0 || lvl > MissedWindow {
+			return nil, newUserError("err_missed_level", MissedWindow, oneLvl)
+		}
+		levels = append(levels, lvl)
+	}
+	sort.Ints(levels)
+	return levels, nil
+}
+
+// Пороги оповещения мастерноды (свои или по умолчанию)
+func getMissedLevels(oneNode nodeData) []int {
+	if len(oneNode.MissedLevels) > 0 {
+		return oneNode.MissedLevels
+	}
+	return MissedLevels
+}
+
+// Количество пропущенных блоков мастерноды и размер заполненного окна
+func getMissedBlocks(pubKey string) (int, int) {
+	missedMutex.RLock()
+	defer missedMutex.RUnlock()
+	missed := 0
+	for _, oneBlock := range missedBlocks[pubKey] {
+		if oneBlock {
+			missed++
+		}
+	}
+	return missed, len(missedBlocks[pubKey])
+}
+
+// Высота последнего блока сети (0 - ещё не известна)
+func getLatestHeight() int {
+	missedMutex.RLock()
+	defer missedMutex.RUnlock()
+	return latestHeight
+}
+
+// Обход новых блоков мастерноды и учёт подписей отслеживаемых валидаторов
+func ReturnBlocks() {
+	// отслеживаемые мастерноды
+	watched := map[string]bool{}
+	for _, pubKey := range allUser.PubKeys() {
+		watched[pubKey] = true
+	}
+
+	sdk := m.SDK{
+		MnAddress: getMnAddress(),
+	}
+
+	var status m.ResultNetwork
+	err := mnCall(sdk.MnAddress, func() error {
+		var err error
+		status, err = sdk.GetStatus()
+		return err
+	})
+	if err != nil {
+		nodeError(err)
+		return
+	}
+	missedMutex.Lock()
+	latestHeight = status.LatestBlockHeight
+	missedMutex.Unlock()
+
+	// после запуска или долгого перерыва смотрим только последнее окно
+	if lastBlock < status.LatestBlockHeight-MissedWindow {
+		lastBlock = status.LatestBlockHeight - MissedWindow
+	}
+
+	for lastBlock < status.LatestBlockHeight {
+		var blck m.BlockResponse
+		err := mnCall(sdk.MnAddress, func() error {
+			var err error
+			blck, err = sdk.GetBlock(lastBlock + 1)
+			return err
+		})
+		if err != nil {
+			nodeError(err)
+			return
+		}
+		missedMutex.Lock()
+		for _, oneVld := range blck.Validators {
+			if !watched[oneVld.Pubkey] {
+				continue
+			}
+			wnd := append(missedBlocks[oneVld.Pubkey], !oneVld.Signed)
+			if len(wnd) > MissedWindow {
+				wnd = wnd[len(wnd)-MissedWindow:]
+			}
+			missedBlocks[oneVld.Pubkey] = wnd
+		}
+		missedMutex.Unlock()
+		lastBlock++
+	}
+
+	// забываем мастерноды, которые больше не отслеживаются
+	missedMutex.Lock()
+	for pubKey, _ := range missedBlocks {
+		if !watched[pubKey] {
+			delete(missedBlocks, pubKey)
+		}
+	}
+	missedMutex.Unlock()
+}
+
+// Проверка порогов пропуска блоков, возвращает текст оповещения ("" - оповещать не нужно)
+func checkMissedAlert(chatID int64, lang string, oneNode nodeData) string {
+	alrt, ok := nodeAlerts[alertKey(chatID, oneNode.PubKey)]
+	if !ok {
+		return ""
+	}
+	missed, wnd := getMissedBlocks(oneNode.PubKey)
+
+	// самый высокий достигнутый порог
+	level := 0
+	for _, lvl := range getMissedLevels(oneNode) {
+		if missed >= lvl {
+			level = lvl
+		}
+	}
+
+	retTxt := ""
+	if level > alrt.MissedLevel && oneNode.Notification {
+		retTxt = tr(lang, "alert_missed", oneNode.Label, missed, wnd)
+	} else if missed == 0 && alrt.MissedLevel > 0 && oneNode.Notification {
+		retTxt = tr(lang, "alert_signing", oneNode.Label)
+	}
+	if level > alrt.MissedLevel || missed == 0 {
+		alrt.MissedLevel = level
+	}
+	return retTxt
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Результатов поиска мастернод на одной странице
+const searchPageSize = 5
+
+// Ссылка на мастерноду пользователя в данных кнопки: индекс и начало паблик-кея,
+// чтобы кнопка старого сообщения не сработала на другой мастерноде после /node_del
+func nodeRef(iN int, oNd nodeData) string {
+	pkShort := oNd.PubKey
+	if len(pkShort) > 10 {
+		pkShort = pkShort[:10]
+	}
+	return fmt.Sprintf("%d:%s", iN, pkShort)
+}
+
+// Поиск мастерноды пользователя по ссылке из кнопки, -1 если не найдена
+func findNodeRef(usr usrData, ref string) int {
+	args := strings.SplitN(ref, ":", 2)
+	if len(args) != 2 {
+		return -1
+	}
+	iN, err := strconv.Atoi(args[0])
+	if err != nil || iN < 0 || iN >= len(usr.Nodes) {
+		return -1
+	}
+	if nodeRef(iN, usr.Nodes[iN]) != ref {
+		return -1
+	}
+	return iN
+}
+
+// Данные кнопки управления мастернодой: действие, ID пользователя, открывшего сообщение,
+// и аргумент. Нажать такую кнопку может только он (в группе её видят все)
+func userBtnData(action string, userID int, arg string) string {
+	if arg == "" {
+		return fmt.Sprintf("%s:%d", action, userID)
+	}
+	return fmt.Sprintf("%s:%d:%s", action, userID, arg)
+}
+
+// Проверка ID пользователя из данных кнопки, возвращает аргумент кнопки
+// (false - кнопку нажал другой пользователь)
+func checkBtnUser(arg string, userID int) (string, bool) {
+	args := strings.SplitN(arg, ":", 2)
+	if args[0] != strconv.Itoa(userID) {
+		return "", false
+	}
+	if len(args) == 2 {
+		return args[1], true
+	}
+	return "", true
+}
+
+// Кнопки списка мастернод пользователя: по кнопке на мастерноду
+func nodeListKeyboard(userID int, usr usrData) *tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	row := []tgbotapi.InlineKeyboardButton{}
+	for iN, oNd := range usr.Nodes {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(oNd.Label, userBtnData("info", userID, nodeRef(iN, oNd))))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = []tgbotapi.InlineKeyboardButton{}
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &btnKeyboard
+}
+
+// Кнопки управления мастернодой пользователя
+func nodeKeyboard(lang string, userID int, iN int, oNd nodeData) *tgbotapi.InlineKeyboardMarkup {
+	ref := nodeRef(iN, oNd)
+	btnNotif := tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_notif_on"), userBtnData("notif", userID, ref))
+	if oNd.Notification {
+		btnNotif = tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_notif_off"), userBtnData("notif", userID, ref))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(btnNotif)}
+	// вкл/откл мастерноды - только если привязан PrivKey
+	if oNd.PrivKey != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_cand_on"), userBtnData("cand", userID, "on:"+ref)),
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_cand_off"), userBtnData("cand", userID, "off:"+ref)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_list"), userBtnData("list", userID, "")),
+	))
+	btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &btnKeyboard
+}
+
+// Список мастернод пользователя: текст и кнопки для userID
+func nodeListMessage(lang string, userID int, usr usrData) (string, *tgbotapi.InlineKeyboardMarkup) {
+	if len(usr.Nodes) == 0 {
+		return tr(lang, "no_nodes_info"), nil
+	}
+	retTxt := tr(lang, "node_count", len(usr.Nodes))
+	for _, oNd := range usr.Nodes {
+		retTxt += "\n\n" + getNodeInfoString(lang, oNd)
+	}
+	return retTxt, nodeListKeyboard(userID, usr)
+}
+
+// Страница результатов поиска мастернод (page с 0): текст и кнопки
+func searchMessage(lang string, search string, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	resSrch := searchValid(search)
+	amntPages := (len(resSrch) + searchPageSize - 1) / searchPageSize
+	if page >= amntPages {
+		page = amntPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	retTxt := tr(lang, "search_found", len(resSrch))
+	if allCand.Partial() {
+		retTxt += tr(lang, "cand_partial")
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for iN := page * searchPageSize; iN < len(resSrch) && iN < (page+1)*searchPageSize; iN++ {
+		oNd := resSrch[iN]
+		retTxt += "\n\n" + tr(lang, "search_item",
+			iN+1,
+			oNd.PubKey,
+			getNodeStatusString(lang, oNd),
+			oNd.Commission,
+			oNd.TotalStake)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonSwitch(tr(lang, "btn_key", iN+1), oNd.PubKey),
+		))
+	}
+
+	// листать можно, только если строка поиска помещается в данные кнопки (до 64 байт)
+	if amntPages > 1 && len("srch:0000:"+search) <= 64 {
+		retTxt += "\n\n" + tr(lang, "search_page", page+1, amntPages)
+		navRow := []tgbotapi.InlineKeyboardButton{}
+		if page > 0 {
+			navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_prev"), fmt.Sprintf("srch:%d:%s", page-1, search)))
+		}
+		if page < amntPages-1 {
+			navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_next"), fmt.Sprintf("srch:%d:%s", page+1, search)))
+		}
+		rows = append(rows, navRow)
+	}
+	if len(rows) == 0 {
+		return retTxt, nil
+	}
+	btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return retTxt, &btnKeyboard
+}
+
+// Обработка нажатия кнопки: сообщение с кнопкой изменяется на месте
+func handleCallback(bot *tgbotapi.BotAPI, store UserStore, cq *tgbotapi.CallbackQuery) {
+	fmt.Printf("[%s] callback %s\n", cq.From.UserName, cq.Data)
+
+	// кнопки бывают только у сообщений бота в чате
+	if cq.Message == nil {
+		bot.AnswerCallbackQuery(tgbotapi.NewCallback(cq.ID, ""))
+		return
+	}
+	chatID := cq.Message.Chat.ID
+	lang := chatLang(store, chatID, cq.From)
+	oUsr := getUser(chatID)
+
+	args := strings.SplitN(cq.Data, ":", 2)
+	arg := ""
+	if len(args) == 2 {
+		arg = args[1]
+	}
+
+	// управлять мастернодами может только тот, кто открыл сообщение
+	switch args[0] {
+	case "list", "info", "notif", "cand":
+		var okUser bool
+		arg, okUser = checkBtnUser(arg, cq.From.ID)
+		if !okUser {
+			_, err := bot.AnswerCallbackQuery(tgbotapi.NewCallback(cq.ID, tr(lang, "btn_other_user")))
+			if err != nil {
+				fmt.Println("Ошибка ответа на кнопку:", err)
+			}
+			return
+		}
+	}
+
+	answer := ""
+	reply := ""
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	switch args[0] {
+
+	// список мастернод пользователя
+	case "list":
+		reply, keyboard = nodeListMessage(lang, cq.From.ID, oUsr)
+
+	// информация о мастерноде
+	case "info":
+		if iN := findNodeRef(oUsr, arg); iN != -1 {
+			reply = getNodeInfoString(lang, oUsr.Nodes[iN])
+			keyboard = nodeKeyboard(lang, cq.From.ID, iN, oUsr.Nodes[iN])
+		}
+
+	// вкл/откл оповещения
+	case "notif":
+		if iN := findNodeRef(oUsr, arg); iN != -1 {
+			answer = editNodeNotif(store, chatID, lang, iN)
+			oUsr = getUser(chatID)
+			reply = getNodeInfoString(lang, oUsr.Nodes[iN])
+			keyboard = nodeKeyboard(lang, cq.From.ID, iN, oUsr.Nodes[iN])
+		}
+
+	// запрос подтверждения вкл/откл мастерноды
+	case "cand":
+		cArgs := strings.SplitN(arg, ":", 2)
+		if len(cArgs) == 2 {
+			statusMnode, okCommand := parseOnOff(cArgs[0])
+			if iN := findNodeRef(oUsr, cArgs[1]); iN != -1 && okCommand && oUsr.Nodes[iN].PrivKey != "" {
+				reply, keyboard = askCandidate(lang, chatID, cq.From.ID, oUsr.Nodes[iN], statusMnode)
+			}
+		}
+
+	// транзакция подтверждена или отменена
+	case "txok":
+		reply = runConfirm(bot, lang, chatID, cq.From.ID, arg)
+	case "txno":
+		reply = cancelConfirm(lang, chatID, cq.From.ID, arg)
+
+	// листание списка валидаторов
+	case "top":
+		tArgs := strings.SplitN(arg, ":", 2)
+		if len(tArgs) == 2 {
+			page, errPage := strconv.Atoi(tArgs[0])
+			amnt, errAmnt := strconv.Atoi(tArgs[1])
+			if errPage == nil && errAmnt == nil {
+				reply, keyboard = topMessage(lang, amnt, page)
+			}
+		}
+
+	// листание результатов поиска
+	case "srch":
+		sArgs := strings.SplitN(arg, ":", 2)
+		if len(sArgs) == 2 {
+			page, err := strconv.Atoi(sArgs[0])
+			if err == nil {
+				reply, keyboard = searchMessage(lang, sArgs[1], page)
+			}
+		}
+	}
+
+	// мастерноду удалили или изменили - кнопка больше не действует
+	if reply == "" {
+		answer = tr(lang, "callback_stale")
+	}
+	_, err := bot.AnswerCallbackQuery(tgbotapi.NewCallback(cq.ID, answer))
+	if err != nil {
+		fmt.Println("Ошибка ответа на кнопку:", err)
+	}
+	if reply == "" {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, reply)
+	edit.ReplyMarkup = keyboard
+	sendMessage(bot, edit)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckBtnUser(t *testing.T) {
+	oNd := nodeData{Label: "node1", PubKey: "Mp" + strings.Repeat("ab", 32)}
+	tests := []struct {
+		name    string
+		data    string
+		userID  int
+		wantArg string
+		wantOk  bool
+	}{
+		{"owner", userBtnData("notif", 42, nodeRef(0, oNd)), 42, nodeRef(0, oNd), true},
+		{"other user", userBtnData("notif", 42, nodeRef(0, oNd)), 7, "", false},
+		{"owner without arg", userBtnData("list", 42, ""), 42, "", true},
+		{"other user without arg", userBtnData("list", 42, ""), 7, "", false},
+		{"old button without user", "notif:" + nodeRef(0, oNd), 42, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.SplitN(tt.data, ":", 2)
+			arg, ok := checkBtnUser(args[1], tt.userID)
+			if arg != tt.wantArg || ok != tt.wantOk {
+				t.Fatalf("checkBtnUser(%q, %d) = %q, %v, want %q, %v", args[1], tt.userID, arg, ok, tt.wantArg, tt.wantOk)
+			}
+			if len(tt.data) > 64 {
+				t.Fatalf("button data %q is longer than 64 bytes", tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Пороги оповещения об изменении стэка (секция [monitor] INI файла, 0 - порог не используется)
+var (
+	StakeChangeAbs float32 = 0 // Оповещать, если стэк изменился больше чем на, монет
+	StakeChangePct float32 = 5 // Оповещать, если стэк изменился больше чем на, %
+)
+
+// Стэк и комиссия мастерноды, о которых последний раз оповещали
+type nodeSnapshot struct {
+	TotalStake float32
+	Commission int
+}
+
+// Снимки отслеживаемых мастернод, ключ - pubkey (только для горутины monitor)
+var nodeSnapshots = map[string]*nodeSnapshot{}
+
+// Изменение стэка и комиссии мастерноды с прошлого снимка
+type nodeChange struct {
+	OldStake, NewStake float32
+	OldComm, NewComm   int
+	Stake, Comm        bool // изменились больше порога
+}
+
+// Превышен ли порог изменения стэка
+func isStakeChanged(oldStake float32, newStake float32) bool {
+	diff := newStake - oldStake
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff == 0 {
+		return false
+	}
+	if StakeChangeAbs > 0 && diff >= StakeChangeAbs {
+		return true
+	}
+	if StakeChangePct > 0 && (oldStake == 0 || diff/oldStake*100 >= StakeChangePct) {
+		return true
+	}
+	return false
+}
+
+// Сравнение отслеживаемых мастернод с прошлым снимком, ключ результата - pubkey.
+// Снимок стэка обновляется только при оповещении, чтобы медленное изменение
+// тоже набрало порог, комиссия - при любом изменении
+func checkNodeChanges() map[string]nodeChange {
+	changes := map[string]nodeChange{}
+	watched := map[string]bool{}
+	for _, pubKey := range allUser.PubKeys() {
+		watched[pubKey] = true
+		cnd, ok := allCand.Get(pubKey)
+		if !ok {
+			continue
+		}
+		snap, ok := nodeSnapshots[pubKey]
+		if !ok {
+			// первый снимок - сравнивать не с чем
+			nodeSnapshots[pubKey] = &nodeSnapshot{TotalStake: cnd.TotalStake, Commission: cnd.Commission}
+			continue
+		}
+		chng := nodeChange{
+			OldStake: snap.TotalStake,
+			NewStake: cnd.TotalStake,
+			OldComm:  snap.Commission,
+			NewComm:  cnd.Commission,
+			Stake:    isStakeChanged(snap.TotalStake, cnd.TotalStake),
+			Comm:     snap.Commission != cnd.Commission,
+		}
+		if !chng.Stake && !chng.Comm {
+			continue
+		}
+		if chng.Stake {
+			snap.TotalStake = cnd.TotalStake
+		}
+		snap.Commission = cnd.Commission
+		changes[pubKey] = chng
+	}
+
+	// забываем мастерноды, которые больше не отслеживаются
+	for pubKey, _ := range nodeSnapshots {
+		if !watched[pubKey] {
+			delete(nodeSnapshots, pubKey)
+		}
+	}
+	return changes
+}
+
+// Текст оповещения об изменении стэка и комиссии мастерноды ("" - оповещать не нужно)
+func getChangeString(lang string, label string, chng nodeChange) string {
+	alrtTxt := []string{}
+	if chng.Comm {
+		alrtTxt = append(alrtTxt, tr(lang, "alert_commission", label, chng.OldComm, chng.NewComm))
+	}
+	if chng.Stake {
+		pct := "-"
+		if chng.OldStake > 0 {
+			pct = fmt.Sprintf("%+.2f%%", (chng.NewStake-chng.OldStake)/chng.OldStake*100)
+		}
+		alrtTxt = append(alrtTxt, tr(lang, "alert_stake", label, chng.OldStake, chng.NewStake, pct))
+	}
+	return strings.Join(alrtTxt, "\n")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Сколько сек. ждать подтверждения транзакции (секция [security] INI файла)
+var ConfirmTimeout int64 = 120
+
+// Комиссия за транзакцию вкл/откл мастерноды (100 единиц по 0.001 монеты) при GasPrice = 1
+const setCandidateFee = 0.1
+
+// Транзакция, ожидающая подтверждения пользователем
+type pendingTx struct {
+	UserID  int       // кто запросил: подтвердить может только он (важно в группах)
+	Code    string    // код для /confirm
+	Expires time.Time // после этого подтвердить уже нельзя
+	Text    string    // что будет отправлено (показывается пользователю)
+	// Отправка транзакции, возвращает текст ответа
+	Run func(bot *tgbotapi.BotAPI, lang string) (string, error)
+}
+
+// Ожидающие подтверждения транзакции, ключ - chatID (у чата одна, новая заменяет старую)
+var (
+	pendingTxs  = map[int64]*pendingTx{}
+	pendingLock sync.Mutex
+)
+
+// Случайный код подтверждения из 6 цифр
+func newConfirmCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Запрос подтверждения транзакции: текст и кнопки для пользователя
+func askConfirm(lang string, chatID int64, userID int, text string, run func(bot *tgbotapi.BotAPI, lang string) (string, error)) (string, *tgbotapi.InlineKeyboardMarkup) {
+	// без случайного кода транзакцию не ждём - такой код можно угадать
+	code, err := newConfirmCode()
+	if err != nil {
+		fmt.Println("ERROR", err)
+		return tr(lang, "confirm_code_error"), nil
+	}
+	now := time.Now()
+	pTx := &pendingTx{
+		UserID:  userID,
+		Code:    code,
+		Expires: now.Add(time.Duration(ConfirmTimeout) * time.Second),
+		Text:    text,
+		Run:     run,
+	}
+	pendingLock.Lock()
+	cleanPendingTxs(now)
+	pendingTxs[chatID] = pTx
+	pendingLock.Unlock()
+
+	retTxt := text + "\n\n" + tr(lang, "confirm_ask", pTx.Code, ConfirmTimeout)
+	btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_confirm"), "txok:"+pTx.Code),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_cancel"), "txno:"+pTx.Code),
+	))
+	return retTxt, &btnKeyboard
+}
+
+// Удаление транзакций, которые уже нельзя подтвердить: брошенные запросы не копятся (под pendingLock)
+func cleanPendingTxs(now time.Time) {
+	for chatID, pTx := range pendingTxs {
+		if now.After(pTx.Expires) {
+			delete(pendingTxs, chatID)
+		}
+	}
+}
+
+// Подтверждение транзакции кодом: отправляет её, если код верный и время не вышло
+func runConfirm(bot *tgbotapi.BotAPI, lang string, chatID int64, userID int, code string) string {
+	pendingLock.Lock()
+	pTx, ok := pendingTxs[chatID]
+	if ok && pTx.UserID == userID && pTx.Code == strings.TrimSpace(code) {
+		// второй раз ту же транзакцию не отправим
+		delete(pendingTxs, chatID)
+	}
+	pendingLock.Unlock()
+
+	if !ok {
+		return tr(lang, "confirm_none")
+	}
+	if pTx.UserID != userID {
+		return tr(lang, "confirm_other_user")
+	}
+	if pTx.Code != strings.TrimSpace(code) {
+		return tr(lang, "confirm_wrong_code")
+	}
+	if time.Now().After(pTx.Expires) {
+		return tr(lang, "confirm_expired")
+	}
+	retTxt, err := pTx.Run(bot, lang)
+	if err != nil {
+		return tr(lang, "tx_error", err.Error())
+	}
+	return retTxt
+}
+
+// Отмена ожидающей транзакции (code = "" - любой)
+func cancelConfirm(lang string, chatID int64, userID int, code string) string {
+	pendingLock.Lock()
+	defer pendingLock.Unlock()
+	pTx, ok := pendingTxs[chatID]
+	if !ok || (code != "" && pTx.Code != code) {
+		return tr(lang, "confirm_none")
+	}
+	if pTx.UserID != userID {
+		return tr(lang, "confirm_other_user")
+	}
+	delete(pendingTxs, chatID)
+	return tr(lang, "confirm_canceled")
+}
+
+// Мастернода пользователя для подписи подтверждённой транзакции: пока ждали подтверждения,
+// мастерноду могли удалить или сменить ключи (false - подписывать нечем)
+func lookupSignerNode(chatID int64, pubKey string) (nodeData, bool) {
+	oUsr := getUser(chatID)
+	iN := findNode(oUsr, pubKey)
+	if iN == -1 || oUsr.Nodes[iN].PrivKey == "" {
+		return nodeData{}, false
+	}
+	return oUsr.Nodes[iN], true
+}
+
+// Запрос подтверждения вкл/откл мастерноды пользователя
+func askCandidate(lang string, chatID int64, userID int, oNd nodeData, status bool) (string, *tgbotapi.InlineKeyboardMarkup) {
+	stateKey := "state_off"
+	if status {
+		stateKey = "state_on"
+	}
+	text := tr(lang, "candidate_confirm",
+		oNd.Label,
+		getMinString(oNd.PubKey),
+		tr(lang, stateKey),
+		CoinMinter,
+		fmt.Sprintf("%g %s", setCandidateFee, CoinMinter))
+
+	pubKey := oNd.PubKey
+	return askConfirm(lang, chatID, userID, text, func(bot *tgbotapi.BotAPI, lang string) (string, error) {
+		oNd, ok := lookupSignerNode(chatID, pubKey)
+		if !ok {
+			return tr(lang, "callback_stale"), nil
+		}
+		tx, err := SetCandidateTransaction(oNd.UserAddress, oNd.PrivKey, oNd.PubKey, status)
+		if err != nil {
+			return "", err
+		}
+		// о результате сообщим, когда транзакция попадёт в блок
+		go trackTx(bot, chatID, lang, tx, func() string {
+			statusTxt, _ := checkCandidateStatus(lang, pubKey, status)
+			return statusTxt
+		})
+		return tr(lang, "candidate_changed", tx), nil
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAskConfirmCleansExpired(t *testing.T) {
+	pendingLock.Lock()
+	pendingTxs = map[int64]*pendingTx{
+		1: {UserID: 1, Code: "000001", Expires: time.Now().Add(-time.Minute)},
+		2: {UserID: 2, Code: "000002", Expires: time.Now().Add(-time.Second)},
+		3: {UserID: 3, Code: "000003", Expires: time.Now().Add(time.Minute)},
+	}
+	pendingLock.Unlock()
+	defer func() {
+		pendingLock.Lock()
+		pendingTxs = map[int64]*pendingTx{}
+		pendingLock.Unlock()
+	}()
+
+	askConfirm("en", 4, 4, "tx", nil)
+
+	pendingLock.Lock()
+	defer pendingLock.Unlock()
+	for _, chatID := range []int64{1, 2} {
+		if _, ok := pendingTxs[chatID]; ok {
+			t.Errorf("expired confirmation of chat %d left", chatID)
+		}
+	}
+	for _, chatID := range []int64{3, 4} {
+		if _, ok := pendingTxs[chatID]; !ok {
+			t.Errorf("confirmation of chat %d removed", chatID)
+		}
+	}
+}
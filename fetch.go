@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+)
+
+// Настройки запросов к мастерноде (секция [masternode] INI файла)
+var (
+	MnWorkers = 8  // Одновременных запросов кандидатов
+	MnRate    = 20 // Запросов в сек. к одной мастерноде (0 - без ограничения)
+	MnTimeout = 10 // Таймаут одного запроса, сек.
+)
+
+var errTimeout = errors.New("превышено время ожидания ответа мастерноды")
+
+// Ограничение частоты запросов к одной мастерноде
+type rateLimiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+var (
+	limiters     = map[string]*rateLimiter{}
+	limitersLock sync.Mutex
+)
+
+// Ожидание разрешения на запрос к мастерноде
+func mnWait(address string) {
+	if MnRate <= 0 {
+		return
+	}
+	limitersLock.Lock()
+	lmt, ok := limiters[address]
+	if !ok {
+		lmt = &rateLimiter{interval: time.Second / time.Duration(MnRate)}
+		limiters[address] = lmt
+	}
+	limitersLock.Unlock()
+
+	lmt.mu.Lock()
+	now := time.Now()
+	if lmt.next.Before(now) {
+		lmt.next = now
+	}
+	wait := lmt.next.Sub(now)
+	lmt.next = lmt.next.Add(lmt.interval)
+	lmt.mu.Unlock()
+	time.Sleep(wait)
+}
+
+// Таймаут запросов к мастерноде. SDK ходит в мастерноду через http.DefaultClient и
+// своего клиента принять не может, поэтому в DefaultClient ставится транспорт, который
+// ограничивает по времени только запросы к мастернодам из MnAddresses. Остальные
+// запросы через DefaultClient идут как раньше, без таймаута
+func setMnTimeout() {
+	hosts := map[string]bool{}
+	for _, address := range MnAddresses {
+		if mnURL, err := url.Parse(address); err == nil && mnURL.Host != "" {
+			hosts[mnURL.Host] = true
+		}
+	}
+	http.DefaultClient.Transport = &mnTransport{
+		base:    http.DefaultTransport,
+		hosts:   hosts,
+		timeout: time.Duration(MnTimeout) * time.Second,
+	}
+}
+
+// Транспорт с таймаутом запросов к мастернодам (запрос и чтение ответа целиком)
+type mnTransport struct {
+	base    http.RoundTripper
+	hosts   map[string]bool // хосты мастернод, после создания не меняется
+	timeout time.Duration
+}
+
+func (t *mnTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 || !t.hosts[req.URL.Host] {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// Тело ответа, которое снимает таймаут запроса при закрытии
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Запрос к мастерноде с ограничением частоты. По таймауту транспорт сам
+// закрывает соединение, так что зависшая мастернода не оставляет висеть горутины
+func mnCall(address string, call func() error) error {
+	mnWait(address)
+	err := call()
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return errTimeout
+	}
+	return err
+}
+
+// Данные кандидата из SDK в формате бота
+func newCandidateInfo(cnd m.CandidateInfo) candidate_info {
+	return candidate_info{
+		CandidateAddress: cnd.CandidateAddress,
+		TotalStake:       cnd.TotalStake,
+		PubKey:           cnd.PubKey,
+		Commission:       cnd.Commission,
+		CreatedAtBlock:   cnd.CreatedAtBlock,
+		StatusInt:        cnd.StatusInt,
+	}
+}
+
+// Стэки делегатов кандидата из SDK в формате бота (Value в SDK - строка, число - в Value32)
+func newStakesInfo(cnd m.CandidateInfo) []stakes_info {
+	retStakes := []stakes_info{}
+	for _, oneStake := range cnd.Stakes {
+		retStakes = append(retStakes, stakes_info{
+			Owner:      oneStake.Owner,
+			Coin:       oneStake.Coin,
+			Value:      oneStake.Value,
+			BipValue:   oneStake.BipValue,
+			Value32:    oneStake.Value32,
+			BipValue32: oneStake.BipValue32,
+		})
+	}
+	return retStakes
+}
+
+// Получение всех кандидатов одним запросом, а если не вышло - параллельно по одному
+// валидаторов (обязательно) и отслеживаемых мастернод (которые найдутся).
+// Второе значение true - список полный, false - только валидаторы и отслеживаемые
+func fetchCandidates(sdk *m.SDK, vldr []string, watched []string) ([]candidate_info, bool, error) {
+	var allCnd []m.CandidateInfo
+	err := mnCall(sdk.MnAddress, func() error {
+		var err error
+		allCnd, err = sdk.GetCandidates()
+		return err
+	})
+	if err == nil {
+		cndMap := map[string]bool{}
+		retCnd := []candidate_info{}
+		for _, cnd := range allCnd {
+			cndMap[cnd.PubKey] = true
+			retCnd = append(retCnd, newCandidateInfo(cnd))
+		}
+		fullList := true
+		for _, pubKey := range vldr {
+			if !cndMap[pubKey] {
+				fullList = false
+				break
+			}
+		}
+		if fullList {
+			return retCnd, true, nil
+		}
+		// список кандидатов не полный - берём по одному
+	} else {
+		nodeError(err)
+	}
+
+	retCnd, errs := fetchCandidatesPool(sdk, vldr)
+	for _, err := range errs {
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// отслеживаемые мастерноды не из валидаторов, ошибка - мастерноды нет среди кандидатов
+	inList := map[string]bool{}
+	for _, pubKey := range vldr {
+		inList[pubKey] = true
+	}
+	extra := []string{}
+	for _, pubKey := range watched {
+		if !inList[pubKey] {
+			inList[pubKey] = true
+			extra = append(extra, pubKey)
+		}
+	}
+	extraCnd, errs := fetchCandidatesPool(sdk, extra)
+	for iK, err := range errs {
+		if err == nil {
+			retCnd = append(retCnd, extraCnd[iK])
+		}
+	}
+	return retCnd, false, nil
+}
+
+// Параллельное получение кандидатов по одному, не больше MnWorkers запросов одновременно
+func fetchCandidatesPool(sdk *m.SDK, pubKeys []string) ([]candidate_info, []error) {
+	retCnd := make([]candidate_info, len(pubKeys))
+	errs := make([]error, len(pubKeys))
+	jobs := make(chan int)
+
+	workers := MnWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for iW := 0; iW < workers; iW++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for iK := range jobs {
+				var cnd m.CandidateInfo
+				errs[iK] = mnCall(sdk.MnAddress, func() error {
+					var err error
+					cnd, err = sdk.GetCandidate(pubKeys[iK])
+					return err
+				})
+				if errs[iK] == nil {
+					retCnd[iK] = newCandidateInfo(cnd)
+				}
+			}
+		}()
+	}
+	for iK, _ := range pubKeys {
+		jobs <- iK
+	}
+	close(jobs)
+	wg.Wait()
+
+	return retCnd, errs
+}
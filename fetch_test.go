@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Таймаут мастернод на время теста
+func setTestMnTimeout(t *testing.T, timeout int, addresses ...string) {
+	oldTimeout, oldRate, oldAddresses := MnTimeout, MnRate, MnAddresses
+	MnTimeout, MnRate, MnAddresses = timeout, 0, addresses
+	setMnTimeout()
+	t.Cleanup(func() {
+		MnTimeout, MnRate, MnAddresses = oldTimeout, oldRate, oldAddresses
+		setMnTimeout()
+	})
+}
+
+func TestMnCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// зависшая мастернода
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	setTestMnTimeout(t, 1, srv.URL)
+
+	goBefore := runtime.NumGoroutine()
+	start := time.Now()
+	err := mnCall(srv.URL, func() error {
+		res, err := http.Get(srv.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	if err != errTimeout {
+		t.Fatalf("mnCall() = %v, want errTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("mnCall() took %s, want about %ds", elapsed, MnTimeout)
+	}
+
+	// соединение закрыто, запрос не остался висеть
+	time.Sleep(100 * time.Millisecond)
+	if goAfter := runtime.NumGoroutine(); goAfter > goBefore+2 {
+		t.Fatalf("goroutines: %d before, %d after timeout", goBefore, goAfter)
+	}
+}
+
+func TestMnTimeoutOtherHosts(t *testing.T) {
+	// медленный, но не зависший сервер - не мастернода
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	setTestMnTimeout(t, 1, "http://127.0.0.1:1")
+
+	if http.DefaultClient.Timeout != 0 {
+		t.Fatalf("http.DefaultClient.Timeout = %s, want 0", http.DefaultClient.Timeout)
+	}
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request to other host: %v", err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("body = %q, %v", body, err)
+	}
+}
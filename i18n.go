@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Каталоги сообщений бота (секция [lang] INI файла)
+var (
+	LangDir     = "lang" // Папка с каталогами сообщений: ru.ini, en.ini...
+	LangDefault = "ru"   // Язык по умолчанию и для администраторов
+)
+
+// Сообщения по языкам: язык -> ключ -> текст (заполняется при запуске, дальше только чтение)
+var catalogs = map[string]map[string]string{}
+
+// Загрузка всех каталогов *.ini из папки, язык - имя файла
+func loadCatalogs(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ini"))
+	if err != nil {
+		return err
+	}
+	for _, fileName := range files {
+		cfg, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, fileName)
+		if err != nil {
+			return err
+		}
+		lang := strings.TrimSuffix(filepath.Base(fileName), ".ini")
+		msgs := map[string]string{}
+		for key, val := range cfg.Section("").KeysHash() {
+			// в однострочных значениях перевод строки записывается как \n
+			msgs[key] = strings.Replace(val, `\n`, "\n", -1)
+		}
+		catalogs[lang] = msgs
+	}
+	if _, ok := catalogs[LangDefault]; !ok {
+		return fmt.Errorf("нет каталога сообщений языка по умолчанию: %s", filepath.Join(dir, LangDefault+".ini"))
+	}
+	return nil
+}
+
+// Текст сообщения на языке lang. Если перевода нет - на языке по умолчанию
+func tr(lang string, key string, args ...interface{}) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[LangDefault][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Список доступных языков
+func langList() []string {
+	langs := []string{}
+	for lang, _ := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Язык из language_code Telegram (en-US -> en), "" - если каталога нет
+func normLang(code string) string {
+	lang := strings.ToLower(strings.SplitN(strings.Replace(code, "_", "-", -1), "-", 2)[0])
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return ""
+}
+
+// Язык пользователя
+func userLang(usr usrData) string {
+	if _, ok := catalogs[usr.Lang]; ok {
+		return usr.Lang
+	}
+	return LangDefault
+}
+
+// Ошибка для пользователя: ключ сообщения и параметры, переводится при выводе
+type userError struct {
+	Key  string
+	Args []interface{}
+}
+
+func newUserError(key string, args ...interface{}) error {
+	return &userError{Key: key, Args: args}
+}
+
+func (e *userError) Error() string {
+	return tr(LangDefault, e.Key, e.Args...)
+}
+
+// Текст ошибки на языке пользователя
+func trErr(lang string, err error) string {
+	if uErr, ok := err.(*userError); ok {
+		return tr(lang, uErr.Key, uErr.Args...)
+	}
+	return err.Error()
+}
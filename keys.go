@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Префикс зашифрованного приватного ключа в БД
+const encKeyPrefix = "enc:"
+
+// Переменная окружения с мастер-ключом (hex, 32 байта)
+const masterKeyEnv = "TBOT_MASTER_KEY"
+
+var masterAEAD cipher.AEAD // nil - мастер-ключ не задан
+
+// Загрузка мастер-ключа из файла или переменной окружения
+func loadMasterKey(fileName string) error {
+	keyHex := os.Getenv(masterKeyEnv)
+	if fileName != "" {
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		keyHex = string(data)
+	}
+	keyHex = strings.TrimSpace(keyHex)
+	if keyHex == "" {
+		return nil
+	}
+
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return fmt.Errorf("мастер-ключ должен быть в hex: %s", err.Error())
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("мастер-ключ должен быть 32 байта, а не %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	masterAEAD, err = cipher.NewGCM(block)
+	return err
+}
+
+// Зашифрован ли приватный ключ
+func isEncryptedKey(privKey string) bool {
+	return strings.HasPrefix(privKey, encKeyPrefix)
+}
+
+// Шифрование приватного ключа мастер-ключом (AES-256-GCM)
+func encryptKey(privKey string) (string, error) {
+	if privKey == "" || isEncryptedKey(privKey) {
+		return privKey, nil
+	}
+	if masterAEAD == nil {
+		return "", errors.New("не задан мастер-ключ для шифрования приватных ключей")
+	}
+	nonce := make([]byte, masterAEAD.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := masterAEAD.Seal(nonce, nonce, []byte(privKey), nil)
+	return encKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Расшифровка приватного ключа (только в памяти, перед подписью транзакции)
+func decryptKey(privKey string) (string, error) {
+	if !isEncryptedKey(privKey) {
+		// старая запись, ещё не прошедшая migrate_keys
+		return privKey, nil
+	}
+	if masterAEAD == nil {
+		return "", errors.New("не задан мастер-ключ для расшифровки приватного ключа")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(privKey, encKeyPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < masterAEAD.NonceSize() {
+		return "", errors.New("повреждён зашифрованный приватный ключ")
+	}
+	nonce := sealed[:masterAEAD.NonceSize()]
+	plain, err := masterAEAD.Open(nil, nonce, sealed[masterAEAD.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("не удалось расшифровать приватный ключ (другой мастер-ключ?)")
+	}
+	return string(plain), nil
+}
+
+// Шифрование всех незашифрованных приватных ключей в БД
+func migrateKeys(store UserStore) error {
+	if masterAEAD == nil {
+		return errors.New("не задан мастер-ключ для шифрования приватных ключей")
+	}
+	amnt := 0
+	for _, oneUsr := range allUser.Snapshot() {
+		changed := false
+		for iN, _ := range oneUsr.Nodes {
+			if oneUsr.Nodes[iN].PrivKey == "" || isEncryptedKey(oneUsr.Nodes[iN].PrivKey) {
+				continue
+			}
+			encKey, err := encryptKey(oneUsr.Nodes[iN].PrivKey)
+			if err != nil {
+				return err
+			}
+			oneUsr.Nodes[iN].PrivKey = encKey
+			changed = true
+			amnt++
+		}
+		if changed {
+			if err := store.UpdateUser(oneUsr); err != nil {
+				return err
+			}
+			allUser.Add(oneUsr)
+		}
+	}
+	fmt.Printf("Зашифровано приватных ключей: %d\n", amnt)
+	return nil
+}
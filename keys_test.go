@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testMasterKey  = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	testMasterKey2 = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+	testPrivKey    = "07bc17abdcee8b971bb8723e36fe9d2523306d5ab2d683631693238e0f9df142"
+)
+
+// Установка мастер-ключа для теста
+func setTestMasterKey(t *testing.T, keyHex string) {
+	t.Setenv(masterKeyEnv, keyHex)
+	if err := loadMasterKey(""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { masterAEAD = nil })
+}
+
+func TestEncryptKey(t *testing.T) {
+	setTestMasterKey(t, testMasterKey)
+
+	tests := []struct {
+		name    string
+		privKey string
+		sealed  bool // ожидается зашифрованный ключ
+	}{
+		{"plain", testPrivKey, true},
+		{"empty", "", false},
+		{"already encrypted", "enc:AAAA", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encKey, err := encryptKey(tt.privKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.sealed {
+				if encKey != tt.privKey {
+					t.Fatalf("encryptKey(%q) = %q, want unchanged", tt.privKey, encKey)
+				}
+				return
+			}
+			if !isEncryptedKey(encKey) || strings.Contains(encKey, tt.privKey) {
+				t.Fatalf("encryptKey(%q) = %q, want sealed", tt.privKey, encKey)
+			}
+			plain, err := decryptKey(encKey)
+			if err != nil || plain != tt.privKey {
+				t.Fatalf("decryptKey() = %q, %v, want %q", plain, err, tt.privKey)
+			}
+		})
+	}
+}
+
+func TestDecryptKeyWrongMasterKey(t *testing.T) {
+	setTestMasterKey(t, testMasterKey)
+	encKey, err := encryptKey(testPrivKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setTestMasterKey(t, testMasterKey2)
+	if plain, err := decryptKey(encKey); err == nil {
+		t.Fatalf("decryptKey() with other master key = %q, want error", plain)
+	}
+
+	masterAEAD = nil
+	if _, err := decryptKey(encKey); err == nil {
+		t.Fatal("decryptKey() without master key: want error")
+	}
+	if _, err := decryptKey("enc:!!!"); err == nil {
+		t.Fatal("decryptKey() of broken key: want error")
+	}
+}
+
+func TestLoadMasterKeyInvalid(t *testing.T) {
+	for _, keyHex := range []string{"zz", "0001"} {
+		t.Setenv(masterKeyEnv, keyHex)
+		if err := loadMasterKey(""); err == nil {
+			t.Errorf("loadMasterKey(%q): want error", keyHex)
+		}
+	}
+	masterAEAD = nil
+}
+
+func TestMigrateKeys(t *testing.T) {
+	setTestMasterKey(t, testMasterKey)
+	store := newMemoryStore()
+	usr := usrData{ChatID: 1, Nodes: []nodeData{
+		{Label: "plain", PubKey: "Mp01", PrivKey: testPrivKey},
+		{Label: "enc", PubKey: "Mp02", PrivKey: "enc:AAAA"},
+		{Label: "nokey", PubKey: "Mp03"},
+	}}
+	store.AddUser(usr)
+	allUser.Load([]usrData{usr})
+	t.Cleanup(func() { allUser.Load(nil) })
+
+	if err := migrateKeys(store); err != nil {
+		t.Fatal(err)
+	}
+	usrs, _ := store.LoadUsers()
+	for _, got := range []usrData{usrs[0], getUser(1)} {
+		if !isEncryptedKey(got.Nodes[0].PrivKey) {
+			t.Errorf("plain key not encrypted: %q", got.Nodes[0].PrivKey)
+		}
+		if plain, err := decryptKey(got.Nodes[0].PrivKey); err != nil || plain != testPrivKey {
+			t.Errorf("decryptKey() = %q, %v, want %q", plain, err, testPrivKey)
+		}
+		if got.Nodes[1].PrivKey != "enc:AAAA" {
+			t.Errorf("encrypted key changed: %q", got.Nodes[1].PrivKey)
+		}
+		if got.Nodes[2].PrivKey != "" {
+			t.Errorf("empty key changed: %q", got.Nodes[2].PrivKey)
+		}
+	}
+}
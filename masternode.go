@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+)
+
+// Настройки мастернод (секция [masternode] INI файла)
+var (
+	MnAddresses []string // Все мастерноды: ADDRESS, ADDRESS_2, ADDRESS_3...
+	MnMaxLag    = 5      // Допустимое отставание по высоте блока от лучшей мастерноды
+	TgAdmins    []int64  // Чаты администраторов бота (секция [telegram] ADMINS)
+)
+
+var (
+	mnMutex    sync.RWMutex
+	mnAllDown  bool // все мастерноды недоступны, администраторы оповещены
+	mnSelected int  // номер выбранной мастерноды в MnAddresses
+)
+
+// Состояние мастерноды при проверке
+type mnHealth struct {
+	Address    string
+	Reachable  bool
+	CatchingUp bool
+	Height     int
+	Err        error
+}
+
+// Адрес выбранной мастерноды для запросов
+func getMnAddress() string {
+	mnMutex.RLock()
+	defer mnMutex.RUnlock()
+	return MnAddress
+}
+
+// Разбор списка чатов администраторов из строки вида "123,456"
+func parseAdmins(str string) ([]int64, error) {
+	admins := []int64{}
+	for _, oneAdm := range strings.Split(str, ",") {
+		oneAdm = strings.TrimSpace(oneAdm)
+		if oneAdm == "" {
+			continue
+		}
+		chatID, err := strconv.ParseInt(oneAdm, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, chatID)
+	}
+	return admins, nil
+}
+
+// Проверка одной мастерноды
+func checkMasternode(address string) mnHealth {
+	hlth := mnHealth{Address: address}
+	sdk := m.SDK{
+		MnAddress: address,
+	}
+	var status m.ResultNetwork
+	err := mnCall(address, func() error {
+		var err error
+		status, err = sdk.GetStatus()
+		return err
+	})
+	if err != nil {
+		mtrNodeErrors.Inc()
+		hlth.Err = err
+		return hlth
+	}
+	hlth.Reachable = true
+	hlth.CatchingUp = status.TmStatus.SyncInfo.CatchingUp
+	hlth.Height = status.LatestBlockHeight
+	return hlth
+}
+
+// Проверка всех мастернод и выбор рабочей (по порядку в INI файле), false - рабочих нет
+func selectMasternode(bot *tgbotapi.BotAPI) bool {
+	allHlth := []mnHealth{}
+	maxHeight := 0
+	for _, address := range MnAddresses {
+		hlth := checkMasternode(address)
+		if hlth.Height > maxHeight {
+			maxHeight = hlth.Height
+		}
+		allHlth = append(allHlth, hlth)
+	}
+
+	selected := -1
+	for iM, hlth := range allHlth {
+		if hlth.Reachable && !hlth.CatchingUp && maxHeight-hlth.Height <= MnMaxLag {
+			selected = iM
+			break
+		}
+	}
+
+	if selected == -1 {
+		fmt.Println("ERROR", "Нет рабочих мастернод!")
+		for _, hlth := range allHlth {
+			fmt.Printf("  %s: доступна=%v синхронизация=%v блок=%d ошибка=%v\n", hlth.Address, hlth.Reachable, hlth.CatchingUp, hlth.Height, hlth.Err)
+		}
+		if !mnAllDown {
+			mnAllDown = true
+			notifyAdmins(bot, tr(LangDefault, "admin_mn_down"))
+		}
+		return false
+	}
+
+	if mnAllDown {
+		mnAllDown = false
+		notifyAdmins(bot, tr(LangDefault, "admin_mn_up", MnAddresses[selected]))
+	}
+	if selected != mnSelected {
+		fmt.Printf("Переключение на мастерноду %s\n", MnAddresses[selected])
+	}
+	mnMutex.Lock()
+	mnSelected = selected
+	MnAddress = MnAddresses[selected]
+	mnMutex.Unlock()
+	return true
+}
+
+// Сообщение всем администраторам бота
+func notifyAdmins(bot *tgbotapi.BotAPI, text string) {
+	for _, chatID := range TgAdmins {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, text))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Адрес для метрик Prometheus (секция [metrics] INI файла), пусто - не запускать
+var MetricsListen string
+
+var (
+	// данные кандидатов из allCand
+	mtrStake = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "minter_validator_total_stake",
+		Help: "Total stake of the validator.",
+	}, []string{"pubkey"})
+	mtrCommission = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "minter_validator_commission",
+		Help: "Commission of the validator, percent.",
+	}, []string{"pubkey"})
+	mtrStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "minter_validator_status",
+		Help: "Status of the candidate: 1 - offline, 2 - online.",
+	}, []string{"pubkey"})
+	mtrMissed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "minter_validator_missed_blocks",
+		Help: "Missed blocks of the watched validator in the last window.",
+	}, []string{"pubkey"})
+
+	// работа бота
+	mtrPollDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tbot_poll_duration_seconds",
+		Help:    "Duration of the validator list poll.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	})
+	mtrNodeErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tbot_masternode_errors_total",
+		Help: "Errors of the masternode API requests.",
+	})
+	mtrSendErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tbot_telegram_send_errors_total",
+		Help: "Errors of sending Telegram messages.",
+	})
+	mtrStale = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tbot_validators_stale",
+		Help: "1 if the last validator list poll failed and the data is stale.",
+	})
+	mtrPartial = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tbot_candidates_partial",
+		Help: "1 if only validators and watched masternodes are loaded instead of the full candidate list.",
+	})
+	mtrUsers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tbot_users",
+		Help: "Number of bot users.",
+	})
+	mtrNodes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tbot_watched_nodes",
+		Help: "Number of masternodes watched by users.",
+	})
+	mtrWatch = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tbot_watch_subscriptions",
+		Help: "Number of /watch subscriptions to masternodes not owned by the user.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(mtrStake, mtrCommission, mtrStatus, mtrMissed,
+		mtrPollDuration, mtrNodeErrors, mtrSendErrors, mtrStale, mtrPartial, mtrUsers, mtrNodes, mtrWatch)
+}
+
+// Запуск HTTP сервера с /metrics
+func startMetrics() {
+	if MetricsListen == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		fmt.Printf("Метрики: http://%s/metrics\n", MetricsListen)
+		err := http.ListenAndServe(MetricsListen, mux)
+		fmt.Println("Ошибка сервера метрик:", err)
+	}()
+}
+
+// Обновление метрик после очередного опроса мастерноды
+func updateMetrics() {
+	if allCand.Partial() {
+		mtrPartial.Set(1)
+	} else {
+		mtrPartial.Set(0)
+	}
+	mtrStake.Reset()
+	mtrCommission.Reset()
+	mtrStatus.Reset()
+	for _, oneNode := range allCand.All() {
+		mtrStake.WithLabelValues(oneNode.PubKey).Set(float64(oneNode.TotalStake))
+		mtrCommission.WithLabelValues(oneNode.PubKey).Set(float64(oneNode.Commission))
+		mtrStatus.WithLabelValues(oneNode.PubKey).Set(float64(oneNode.StatusInt))
+	}
+
+	// пропуски блоков и своих мастернод, и подписок
+	mtrMissed.Reset()
+	for _, pubKey := range allUser.PubKeys() {
+		missed, _ := getMissedBlocks(pubKey)
+		mtrMissed.WithLabelValues(pubKey).Set(float64(missed))
+	}
+
+	amntNodes, amntWatch := 0, 0
+	usrs := allUser.Snapshot()
+	for _, oneUser := range usrs {
+		amntNodes += len(oneUser.Nodes)
+		amntWatch += len(oneUser.Watch)
+	}
+	mtrUsers.Set(float64(len(usrs)))
+	mtrNodes.Set(float64(amntNodes))
+	mtrWatch.Set(float64(amntWatch))
+}
+
+// Ошибка запроса к мастерноде
+func nodeError(err error) {
+	mtrNodeErrors.Inc()
+	fmt.Println(err.Error())
+}
+
+// Отправка сообщения в Telegram с учётом ошибок
+func sendMessage(bot *tgbotapi.BotAPI, msg tgbotapi.Chattable) {
+	_, err := bot.Send(msg)
+	if err != nil {
+		mtrSendErrors.Inc()
+		fmt.Println("Ошибка отправки сообщения:", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUpdateMetricsWatch(t *testing.T) {
+	resetRegistries(t)
+	store := newMemoryStore()
+	addUser(store, usrData{ChatID: 1, Nodes: []nodeData{{Label: "node1", PubKey: "Mp1"}}, Watch: []watchData{{PubKey: "Mp2"}}})
+	addUser(store, usrData{ChatID: 2, Watch: []watchData{{PubKey: "Mp2"}, {PubKey: "Mp3"}}})
+
+	updateMetrics()
+	if got := testutil.ToFloat64(mtrUsers); got != 2 {
+		t.Errorf("tbot_users = %g, want 2", got)
+	}
+	if got := testutil.ToFloat64(mtrNodes); got != 1 {
+		t.Errorf("tbot_watched_nodes = %g, want 1", got)
+	}
+	if got := testutil.ToFloat64(mtrWatch); got != 3 {
+		t.Errorf("tbot_watch_subscriptions = %g, want 3", got)
+	}
+}
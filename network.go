@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Валидаторов на одной странице /top
+const topPageSize = 10
+
+// Валидаторов в /top по умолчанию
+const topDefault = 10
+
+// Валидаторы по убыванию стэка (копия, список реестра не меняется)
+func getTopValid() []candidate_info {
+	valid := allCand.Valid()
+	topValid := make([]candidate_info, len(valid))
+	copy(topValid, valid)
+	sort.SliceStable(topValid, func(i, j int) bool { return topValid[i].TotalStake > topValid[j].TotalStake })
+	return topValid
+}
+
+// Сводка по сети: количество валидаторов, общий стэк, медиана комиссии, блок и порог входа
+func networkMessage(lang string) string {
+	valid := allCand.Valid()
+	if len(valid) == 0 {
+		return tr(lang, "err_cand_not_loaded")
+	}
+
+	var totalStake float32
+	commissions := []int{}
+	for _, oneNode := range valid {
+		totalStake += oneNode.TotalStake
+		commissions = append(commissions, oneNode.Commission)
+	}
+	sort.Ints(commissions)
+	median := float64(commissions[len(commissions)/2])
+	if len(commissions)%2 == 0 {
+		median = float64(commissions[len(commissions)/2-1]+commissions[len(commissions)/2]) / 2
+	}
+
+	retTxt := tr(lang, "network_info",
+		len(valid),
+		len(allCand.All()),
+		totalStake,
+		median,
+		getLatestHeight(),
+		getValidCutoff(valid))
+	if allCand.Partial() {
+		retTxt += tr(lang, "cand_partial")
+	}
+	if validUpdated, validStale := allCand.Updated(); validStale {
+		retTxt += tr(lang, "node_info_stale", validUpdated.Format("02.01.2006 15:04:05"))
+	}
+	return retTxt
+}
+
+// Страница списка валидаторов по стэку (первые amnt, page с 0): текст и кнопки
+func topMessage(lang string, amnt int, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	topValid := getTopValid()
+	if len(topValid) == 0 {
+		return tr(lang, "err_cand_not_loaded"), nil
+	}
+	if amnt <= 0 || amnt > len(topValid) {
+		amnt = len(topValid)
+	}
+	amntPages := (amnt + topPageSize - 1) / topPageSize
+	if page >= amntPages {
+		page = amntPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	retTxt := tr(lang, "top_title", amnt, len(topValid))
+	for iN := page * topPageSize; iN < amnt && iN < (page+1)*topPageSize; iN++ {
+		oNd := topValid[iN]
+		retTxt += "\n" + tr(lang, "top_item",
+			iN+1,
+			getMinString(oNd.PubKey),
+			oNd.TotalStake,
+			oNd.Commission)
+	}
+	if amntPages == 1 {
+		return retTxt, nil
+	}
+
+	retTxt += "\n\n" + tr(lang, "search_page", page+1, amntPages)
+	navRow := []tgbotapi.InlineKeyboardButton{}
+	if page > 0 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_prev"), fmt.Sprintf("top:%d:%d", page-1, amnt)))
+	}
+	if page < amntPages-1 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn_next"), fmt.Sprintf("top:%d:%d", page+1, amnt)))
+	}
+	btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(navRow)
+	return retTxt, &btnKeyboard
+}
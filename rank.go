@@ -0,0 +1,175 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Пороги оповещения о месте и запасе стэка по умолчанию (секция [monitor] INI файла, 0 - не оповещать)
+var (
+	RankLimit           = 0   // Оповещать, если место мастерноды по стэку ниже
+	StakeMargin float32 = 0.0 // Оповещать, если запас стэка над последним местом валидатора меньше, %
+)
+
+// Место мастерноды среди включенных кандидатов по стэку
+type stakeRank struct {
+	Rank    int     // место по стэку среди включенных кандидатов (с 1)
+	Online  int     // всего включенных кандидатов
+	Cutoff  float32 // стэк последнего места в валидаторах (минимальный стэк валидатора)
+	Margin  float32 // запас стэка над Cutoff, % от Cutoff (меньше 0 - не в валидаторах)
+	InValid bool    // в списке валидаторов
+}
+
+// Стэк последнего места в валидаторах: минимальный стэк валидатора (0 - валидаторов нет).
+// Тот же порог /network показывает как стэк для входа в валидаторы
+func getValidCutoff(valid []candidate_info) float32 {
+	if len(valid) == 0 {
+		return 0
+	}
+	cutoff := valid[0].TotalStake
+	for _, oneNode := range valid {
+		if oneNode.TotalStake < cutoff {
+			cutoff = oneNode.TotalStake
+		}
+	}
+	return cutoff
+}
+
+// Запас стэка над порогом, % от порога
+func stakeMarginPct(stake float32, cutoff float32) float32 {
+	if cutoff <= 0 {
+		return 100
+	}
+	return (stake - cutoff) / cutoff * 100
+}
+
+// Места и запас стэка включенных кандидатов, ключ - pubkey. Выключенные кандидаты
+// место в валидаторах не занимают, поэтому не учитываются. Порог для всех один -
+// стэк последнего места в валидаторах (getValidCutoff). По неполному списку кандидатов
+// места неверны, поэтому до загрузки полного списка мест нет и оповещения не проверяются
+func getStakeRanks() map[string]stakeRank {
+	ranks := map[string]stakeRank{}
+	valid := allCand.Valid()
+	if len(valid) == 0 || allCand.Partial() {
+		return ranks
+	}
+	inValid := map[string]bool{}
+	for _, oneNode := range valid {
+		inValid[oneNode.PubKey] = true
+	}
+	cutoff := getValidCutoff(valid)
+
+	online := []candidate_info{}
+	for _, oneNode := range allCand.All() {
+		// числовое значение статуса: 1 - Offline, 2 - Online
+		if oneNode.StatusInt == 2 {
+			online = append(online, oneNode)
+		}
+	}
+
+	sort.SliceStable(online, func(i, j int) bool { return online[i].TotalStake > online[j].TotalStake })
+	for iN, oneNode := range online {
+		ranks[oneNode.PubKey] = stakeRank{
+			Rank:    iN + 1,
+			Online:  len(online),
+			Cutoff:  cutoff,
+			Margin:  stakeMarginPct(oneNode.TotalStake, cutoff),
+			InValid: inValid[oneNode.PubKey],
+		}
+	}
+	return ranks
+}
+
+// Порог места мастерноды (свой или по умолчанию)
+func getRankLimit(oneNode nodeData) int {
+	if oneNode.RankLimit > 0 {
+		return oneNode.RankLimit
+	}
+	return RankLimit
+}
+
+// Порог запаса стэка мастерноды, % (свой или по умолчанию)
+func getStakeMargin(oneNode nodeData) float32 {
+	if oneNode.StakeMargin > 0 {
+		return oneNode.StakeMargin
+	}
+	return StakeMargin
+}
+
+// Разбор порогов "место процент", 0 - порог по умолчанию
+func parseRankLimits(rankStr string, marginStr string) (int, float32, error) {
+	rank, err := strconv.Atoi(rankStr)
+	if err != nil || rank < 0 {
+		return 0, 0, newUserError("err_rank_limit", rankStr)
+	}
+	margin, err := strconv.ParseFloat(strings.TrimSuffix(marginStr, "%"), 32)
+	if err != nil || margin < 0 || margin > 100 {
+		return 0, 0, newUserError("err_stake_margin", marginStr)
+	}
+	return rank, float32(margin), nil
+}
+
+// Проверка места и запаса стэка мастерноды, возвращает текст оповещения ("" - оповещать не нужно)
+func checkRankAlert(chatID int64, lang string, oneNode nodeData, ranks map[string]stakeRank) string {
+	alrt, ok := nodeAlerts[alertKey(chatID, oneNode.PubKey)]
+	if !ok {
+		return ""
+	}
+	rnk, ok := ranks[oneNode.PubKey]
+	if !ok {
+		return ""
+	}
+
+	alrtTxt := []string{}
+	rankLimit := getRankLimit(oneNode)
+	rankLow := rankLimit > 0 && rnk.Rank > rankLimit
+	if rankLow != alrt.RankAlerted {
+		alrt.RankAlerted = rankLow
+		if rankLow && oneNode.Notification {
+			alrtTxt = append(alrtTxt, tr(lang, "alert_rank_low", oneNode.Label, rnk.Rank, rankLimit))
+		} else if oneNode.Notification {
+			alrtTxt = append(alrtTxt, tr(lang, "alert_rank_ok", oneNode.Label, rnk.Rank))
+		}
+	}
+
+	marginLimit := getStakeMargin(oneNode)
+	marginLow := marginLimit > 0 && rnk.Margin < marginLimit
+	if marginLow != alrt.MarginAlerted {
+		alrt.MarginAlerted = marginLow
+		if marginLow && oneNode.Notification {
+			alrtTxt = append(alrtTxt, tr(lang, "alert_margin_low", oneNode.Label, rnk.Margin, rnk.Cutoff, marginLimit))
+		} else if oneNode.Notification {
+			alrtTxt = append(alrtTxt, tr(lang, "alert_margin_ok", oneNode.Label, rnk.Margin))
+		}
+	}
+	return strings.Join(alrtTxt, "\n")
+}
+
+// Место, запас стэка и пороги оповещения мастерноды пользователя
+func getRankString(lang string, oneNode nodeData) string {
+	if allCand.Partial() {
+		return tr(lang, "rank_partial")
+	}
+	rnk, ok := getStakeRanks()[oneNode.PubKey]
+	if !ok {
+		return tr(lang, "rank_not_found", oneNode.Label)
+	}
+	rankLimit := tr(lang, "rank_off")
+	if lmt := getRankLimit(oneNode); lmt > 0 {
+		rankLimit = strconv.Itoa(lmt)
+	}
+	marginLimit := tr(lang, "rank_off")
+	if lmt := getStakeMargin(oneNode); lmt > 0 {
+		marginLimit = strconv.FormatFloat(float64(lmt), 'g', -1, 32) + "%"
+	}
+	return tr(lang, "rank_info",
+		oneNode.Label,
+		rnk.Rank,
+		rnk.Online,
+		len(allCand.Valid()),
+		rnk.Margin,
+		rnk.Cutoff,
+		rankLimit,
+		marginLimit)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetStakeRanks(t *testing.T) {
+	resetRegistries(t)
+	all := []candidate_info{
+		{PubKey: "Mp1", TotalStake: 1000, StatusInt: 2},
+		{PubKey: "Mp2", TotalStake: 600, StatusInt: 2},
+		{PubKey: "Mp3", TotalStake: 500, StatusInt: 2},
+		// выключенный кандидат с большим стэком место не занимает
+		{PubKey: "MpOff", TotalStake: 5000, StatusInt: 1},
+		{PubKey: "Mp4", TotalStake: 400, StatusInt: 2},
+		{PubKey: "Mp5", TotalStake: 100, StatusInt: 2},
+	}
+	allCand.Set(all[:3], all, false, time.Now())
+
+	tests := []struct {
+		pubKey  string
+		rank    int
+		margin  float32
+		inValid bool
+	}{
+		{"Mp1", 1, 100, true},
+		{"Mp2", 2, 20, true},
+		{"Mp3", 3, 0, true},
+		{"Mp4", 4, -20, false},
+		{"Mp5", 5, -80, false},
+	}
+	ranks := getStakeRanks()
+	if _, ok := ranks["MpOff"]; ok {
+		t.Fatal("offline candidate ranked")
+	}
+	for _, tt := range tests {
+		rnk, ok := ranks[tt.pubKey]
+		if !ok {
+			t.Fatalf("%s not ranked", tt.pubKey)
+		}
+		if rnk.Rank != tt.rank || rnk.Margin != tt.margin || rnk.InValid != tt.inValid || rnk.Cutoff != 500 || rnk.Online != 5 {
+			t.Errorf("%s: %+v, want rank %d, margin %g, in valid %v, cutoff 500, online 5", tt.pubKey, rnk, tt.rank, tt.margin, tt.inValid)
+		}
+	}
+
+	// порог входа в /network - тот же стэк последнего места
+	if cutoff := getValidCutoff(allCand.Valid()); cutoff != 500 {
+		t.Errorf("getValidCutoff() = %g, want 500", cutoff)
+	}
+
+	// неполный список: места неизвестны, оповещения о месте и запасе не проверяются
+	allCand.Set(all[:3], append(all[:3:3], all[4]), true, time.Now())
+	if ranks := getStakeRanks(); len(ranks) != 0 {
+		t.Errorf("partial list: %v, want no ranks", ranks)
+	}
+
+	// валидаторы ещё не загружены
+	allCand.Set(nil, all, false, time.Now())
+	if ranks := getStakeRanks(); len(ranks) != 0 {
+		t.Errorf("without validators: %v, want no ranks", ranks)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+)
+
+// Секреты в тексте: приватные ключи Minter (64 hex) и зашифрованные ключи из БД
+var secretRegexp = regexp.MustCompile(`\b(0x)?[0-9a-fA-F]{64}\b|enc:[0-9A-Za-z+/=]+`)
+
+// Есть ли в тексте приватный ключ
+func containsSecret(text string) bool {
+	return secretRegexp.MatchString(text)
+}
+
+// Замена секретов в тексте для вывода в лог
+func redactSecrets(text string) string {
+	return secretRegexp.ReplaceAllString(text, "***")
+}
+
+// Логгер для tgbotapi (bot.Debug), скрывающий секреты
+type redactLogger struct {
+	log *log.Logger
+}
+
+func newRedactLogger() *redactLogger {
+	return &redactLogger{log: log.New(os.Stderr, "", log.LstdFlags)}
+}
+
+func (l *redactLogger) Println(v ...interface{}) {
+	l.log.Print(redactSecrets(fmt.Sprintln(v...)))
+}
+
+func (l *redactLogger) Printf(format string, v ...interface{}) {
+	l.log.Print(redactSecrets(fmt.Sprintf(format, v...)))
+}
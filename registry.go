@@ -0,0 +1,274 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Пользователи бота в памяти. Доступ из горутины monitor и из обработчика
+// команд, поэтому наружу отдаются только копии записей
+type userRegistry struct {
+	mu       sync.RWMutex
+	users    map[int64]*usrData
+	byPubKey map[string]map[int64]bool // паблик-кей -> chatID пользователей, следящих за мастернодой
+}
+
+func newUserRegistry() *userRegistry {
+	return &userRegistry{
+		users:    map[int64]*usrData{},
+		byPubKey: map[string]map[int64]bool{},
+	}
+}
+
+// Копия записи пользователя (мастерноды не делят память с реестром)
+func copyUser(usr usrData) usrData {
+	nodes := make([]nodeData, len(usr.Nodes))
+	copy(nodes, usr.Nodes)
+	for iN, _ := range nodes {
+		if nodes[iN].MissedLevels != nil {
+			nodes[iN].MissedLevels = append([]int{}, nodes[iN].MissedLevels...)
+		}
+	}
+	usr.Nodes = nodes
+	if usr.Watch != nil {
+		watch := make([]watchData, len(usr.Watch))
+		copy(watch, usr.Watch)
+		for iW, _ := range watch {
+			watch[iW].Alerts = append([]string{}, watch[iW].Alerts...)
+		}
+		usr.Watch = watch
+	}
+	return usr
+}
+
+// Замена всех пользователей (загрузка из БД)
+func (r *userRegistry) Load(usrs []usrData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = map[int64]*usrData{}
+	r.byPubKey = map[string]map[int64]bool{}
+	for _, usr := range usrs {
+		usr1 := copyUser(usr)
+		r.users[usr1.ChatID] = &usr1
+		r.index(&usr1)
+	}
+}
+
+// Паблик-кеи мастернод пользователя и его подписок
+func userPubKeys(usr *usrData) []string {
+	pubKeys := []string{}
+	for _, oneNode := range usr.Nodes {
+		pubKeys = append(pubKeys, oneNode.PubKey)
+	}
+	for _, oneWatch := range usr.Watch {
+		pubKeys = append(pubKeys, oneWatch.PubKey)
+	}
+	return pubKeys
+}
+
+// Добавление паблик-кеев пользователя в индекс (под r.mu)
+func (r *userRegistry) index(usr *usrData) {
+	for _, pubKey := range userPubKeys(usr) {
+		if r.byPubKey[pubKey] == nil {
+			r.byPubKey[pubKey] = map[int64]bool{}
+		}
+		r.byPubKey[pubKey][usr.ChatID] = true
+	}
+}
+
+// Удаление паблик-кеев пользователя из индекса (под r.mu)
+func (r *userRegistry) unindex(usr *usrData) {
+	for _, pubKey := range userPubKeys(usr) {
+		delete(r.byPubKey[pubKey], usr.ChatID)
+		if len(r.byPubKey[pubKey]) == 0 {
+			delete(r.byPubKey, pubKey)
+		}
+	}
+}
+
+// Добавление (или замена) пользователя
+func (r *userRegistry) Add(usr usrData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.users[usr.ChatID]; ok {
+		r.unindex(old)
+	}
+	usr1 := copyUser(usr)
+	r.users[usr1.ChatID] = &usr1
+	r.index(&usr1)
+}
+
+// Копия пользователя по ID чата, false - если не найден
+func (r *userRegistry) Get(chatID int64) (usrData, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usr, ok := r.users[chatID]
+	if !ok {
+		return usrData{}, false
+	}
+	return copyUser(*usr), true
+}
+
+// Изменение пользователя функцией fn под блокировкой, возвращает копию
+// изменённой записи (false - пользователь не найден)
+func (r *userRegistry) Update(chatID int64, fn func(usr *usrData)) (usrData, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usr, ok := r.users[chatID]
+	if !ok {
+		return usrData{}, false
+	}
+	r.unindex(usr)
+	fn(usr)
+	r.index(usr)
+	return copyUser(*usr), true
+}
+
+// Снимок всех пользователей (копии, по возрастанию ChatID)
+func (r *userRegistry) Snapshot() []usrData {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usrs := make([]usrData, 0, len(r.users))
+	for _, usr := range r.users {
+		usrs = append(usrs, copyUser(*usr))
+	}
+	sort.Slice(usrs, func(i, j int) bool { return usrs[i].ChatID < usrs[j].ChatID })
+	return usrs
+}
+
+// Паблик-кеи всех отслеживаемых мастернод
+func (r *userRegistry) PubKeys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pubKeys := make([]string, 0, len(r.byPubKey))
+	for pubKey, _ := range r.byPubKey {
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sort.Strings(pubKeys)
+	return pubKeys
+}
+
+// ID чатов пользователей, следящих за мастернодой
+func (r *userRegistry) Watchers(pubKey string) []int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	chatIDs := []int64{}
+	for chatID, _ := range r.byPubKey[pubKey] {
+		chatIDs = append(chatIDs, chatID)
+	}
+	sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+	return chatIDs
+}
+
+// Количество пользователей
+func (r *userRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.users)
+}
+
+// Кандидаты и валидаторы сети, полученные с мастерноды. Списки заменяются
+// целиком и не изменяются после записи, поэтому их можно отдавать без копирования
+type candRegistry struct {
+	mu       sync.RWMutex
+	valid    []candidate_info // валидаторы
+	all      []candidate_info // все кандидаты (и валидаторы, и выбывшие)
+	byPubKey map[string]int   // паблик-кей -> индекс в all
+	inValid  map[string]bool  // паблик-кей -> в списке валидаторов
+	partial  bool             // в all только валидаторы и отслеживаемые мастерноды (полный список не получен)
+	updated  time.Time        // время последнего успешного обновления
+	stale    bool             // последнее обновление не удалось
+}
+
+func newCandRegistry() *candRegistry {
+	return &candRegistry{
+		byPubKey: map[string]int{},
+		inValid:  map[string]bool{},
+	}
+}
+
+// Замена списков кандидатов и валидаторов, partial - список кандидатов неполный
+func (r *candRegistry) Set(valid []candidate_info, all []candidate_info, partial bool, now time.Time) {
+	byPubKey := map[string]int{}
+	for iC, oneNode := range all {
+		byPubKey[oneNode.PubKey] = iC
+	}
+	inValid := map[string]bool{}
+	for _, oneNode := range valid {
+		inValid[oneNode.PubKey] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.valid = valid
+	r.all = all
+	r.byPubKey = byPubKey
+	r.inValid = inValid
+	r.partial = partial
+	r.updated = now
+}
+
+// Очистка списков
+func (r *candRegistry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.valid = nil
+	r.all = nil
+	r.byPubKey = map[string]int{}
+	r.inValid = map[string]bool{}
+	r.partial = false
+}
+
+// Кандидат по паблик-кею, false - если не найден
+func (r *candRegistry) Get(pubKey string) (candidate_info, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	iC, ok := r.byPubKey[pubKey]
+	if !ok {
+		return candidate_info{}, false
+	}
+	return r.all[iC], true
+}
+
+// Мастернода в списке валидаторов
+func (r *candRegistry) InValid(pubKey string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.inValid[pubKey]
+}
+
+// Все кандидаты (снимок, не изменять)
+func (r *candRegistry) All() []candidate_info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.all
+}
+
+// Валидаторы (снимок, не изменять)
+func (r *candRegistry) Valid() []candidate_info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.valid
+}
+
+// Список кандидатов неполный: места по стэку и количество кандидатов по нему неверны
+func (r *candRegistry) Partial() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.partial
+}
+
+// Отметка об удачном/неудачном обновлении
+func (r *candRegistry) SetStale(stale bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stale = stale
+}
+
+// Время последнего успешного обновления и признак устаревших данных
+func (r *candRegistry) Updated() (time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.updated, r.stale
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Запуск проверок реестров на глобальных allUser/allCand с восстановлением после теста
+func resetRegistries(t *testing.T) {
+	allUser.Load(nil)
+	allCand.Reset()
+	t.Cleanup(func() {
+		allUser.Load(nil)
+		allCand.Reset()
+	})
+}
+
+func TestUserRegistryConcurrent(t *testing.T) {
+	resetRegistries(t)
+	store := newMemoryStore()
+	const users, edits = 4, 50
+	for iU := 0; iU < users; iU++ {
+		addUser(store, usrData{ChatID: int64(iU), UserName: fmt.Sprintf("user%d", iU)})
+	}
+
+	var wg sync.WaitGroup
+	for iU := 0; iU < users; iU++ {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			for iE := 0; iE < edits; iE++ {
+				editUser(store, chatID, func(usr *usrData) {
+					usr.Nodes = append(usr.Nodes, nodeData{
+						Label:        fmt.Sprintf("node%d", iE),
+						PubKey:       fmt.Sprintf("Mp%d-%d", chatID, iE),
+						MissedLevels: []int{iE},
+					})
+				})
+			}
+		}(int64(iU))
+	}
+	// читатели одновременно с записью
+	stop := make(chan struct{})
+	var rd sync.WaitGroup
+	for iR := 0; iR < 4; iR++ {
+		rd.Add(1)
+		go func() {
+			defer rd.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, usr := range allUser.Snapshot() {
+					// копия не делит память с реестром
+					for iN, _ := range usr.Nodes {
+						usr.Nodes[iN].Label = "changed"
+						if len(usr.Nodes[iN].MissedLevels) > 0 {
+							usr.Nodes[iN].MissedLevels[0] = -1
+						}
+					}
+				}
+				for _, pubKey := range allUser.PubKeys() {
+					allUser.Watchers(pubKey)
+				}
+				allUser.Len()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	rd.Wait()
+
+	if got := len(allUser.PubKeys()); got != users*edits {
+		t.Fatalf("PubKeys() = %d keys, want %d", got, users*edits)
+	}
+	stored, _ := store.LoadUsers()
+	storedByID := map[int64]usrData{}
+	for _, usr := range stored {
+		storedByID[usr.ChatID] = usr
+	}
+	for _, usr := range allUser.Snapshot() {
+		if len(usr.Nodes) != edits {
+			t.Fatalf("chat %d: %d nodes, want %d", usr.ChatID, len(usr.Nodes), edits)
+		}
+		for iN, oneNode := range usr.Nodes {
+			if oneNode.Label != fmt.Sprintf("node%d", iN) || oneNode.MissedLevels[0] != iN {
+				t.Fatalf("chat %d: node %d = %+v, changed through a copy", usr.ChatID, iN, oneNode)
+			}
+			if watchers := allUser.Watchers(oneNode.PubKey); len(watchers) != 1 || watchers[0] != usr.ChatID {
+				t.Fatalf("Watchers(%s) = %v, want [%d]", oneNode.PubKey, watchers, usr.ChatID)
+			}
+		}
+		// в БД последняя версия записи
+		if len(storedByID[usr.ChatID].Nodes) != edits {
+			t.Fatalf("chat %d: %d nodes in store, want %d", usr.ChatID, len(storedByID[usr.ChatID].Nodes), edits)
+		}
+	}
+}
+
+func TestCandRegistryConcurrent(t *testing.T) {
+	resetRegistries(t)
+	const rounds = 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for iR := 1; iR <= rounds; iR++ {
+			all := []candidate_info{}
+			for iC := 0; iC < 10; iC++ {
+				all = append(all, candidate_info{PubKey: fmt.Sprintf("Mp%d", iC), TotalStake: float32(iR), StatusInt: 2})
+			}
+			allCand.Set(all[:5], all, false, time.Now())
+			allCand.SetStale(iR%2 == 0)
+		}
+	}()
+	for iG := 0; iG < 4; iG++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for iR := 0; iR < rounds; iR++ {
+				// списки меняются целиком: стэки в одном списке всегда одинаковые
+				all := allCand.All()
+				for _, cnd := range all {
+					if cnd.TotalStake != all[0].TotalStake {
+						t.Errorf("mixed candidate list: %v", all)
+						return
+					}
+				}
+				if cnd, ok := allCand.Get("Mp3"); ok && cnd.PubKey != "Mp3" {
+					t.Errorf("Get(Mp3) = %+v", cnd)
+				}
+				allCand.InValid("Mp3")
+				allCand.Valid()
+				allCand.Updated()
+			}
+		}()
+	}
+	wg.Wait()
+
+	cnd, ok := allCand.Get("Mp7")
+	if !ok || cnd.TotalStake != rounds {
+		t.Fatalf("Get(Mp7) = %+v, %v, want stake %d", cnd, ok, rounds)
+	}
+	if !allCand.InValid("Mp4") || allCand.InValid("Mp5") {
+		t.Fatal("InValid() does not match the last Set")
+	}
+	if _, stale := allCand.Updated(); !stale {
+		t.Fatal("Updated() stale = false, want true after the last SetStale")
+	}
+}
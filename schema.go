@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Миграция схемы записей пользователей: изменяет документ, true - если изменён
+type migration struct {
+	Version int
+	Name    string
+	Apply   func(doc map[string]interface{}) bool
+}
+
+// Версии схемы tabl_bot_usr (база без записи о версии - версия 0)
+var migrations = []migration{
+	{1, "перенос pub_key в pubkey", migratePubKeyField},
+	{2, "перенос мастерноды в список nodes", migrateNodesList},
+}
+
+// Последняя версия схемы
+func schemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Запуск миграций схемы, которые ещё не применены к БД
+func runMigrations(store UserStore) error {
+	dbVersion, err := store.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if dbVersion > schemaVersion() {
+		return fmt.Errorf("версия схемы БД %d новее, чем поддерживает бот (%d)", dbVersion, schemaVersion())
+	}
+
+	for _, mgr := range migrations {
+		if mgr.Version <= dbVersion {
+			continue
+		}
+		docs, err := store.LoadRaw()
+		if err != nil {
+			return err
+		}
+		amnt := 0
+		for _, doc := range docs {
+			if mgr.Apply(doc) {
+				if err := store.SaveRaw(doc); err != nil {
+					return err
+				}
+				amnt++
+			}
+		}
+		if err := store.SetSchemaVersion(mgr.Version); err != nil {
+			return err
+		}
+		fmt.Printf("Миграция схемы БД %d (%s): изменено записей %d\n", mgr.Version, mgr.Name, amnt)
+	}
+	return nil
+}
+
+// Версия 1: editUserKey и delNode писали паблик-кей в поле pub_key, а читался он из pubkey.
+// Значение в pub_key записано позже, поэтому оно и правильное (пустое - мастернода удалена)
+func migratePubKeyField(doc map[string]interface{}) bool {
+	pubKey, ok := doc["pub_key"]
+	if !ok {
+		return false
+	}
+	doc["pubkey"] = pubKey
+	delete(doc, "pub_key")
+	return true
+}
+
+// Версия 2: мастерноды пользователя хранятся списком nodes, а не полями записи
+func migrateNodesList(doc map[string]interface{}) bool {
+	_, hasKey := doc["pubkey"]
+	_, hasAddr := doc["user_address"]
+	_, hasPriv := doc["priv_key"]
+	_, hasNotif := doc["notification"]
+	if !hasKey && !hasAddr && !hasPriv && !hasNotif {
+		return false
+	}
+
+	pubKey, _ := doc["pubkey"].(string)
+	if pubKey != "" {
+		usrAddr, _ := doc["user_address"].(string)
+		privKey, _ := doc["priv_key"].(string)
+		notif, _ := doc["notification"].(bool)
+		nodes, _ := doc["nodes"].([]interface{})
+		doc["nodes"] = append(nodes, map[string]interface{}{
+			"label":        fmt.Sprintf("node%d", len(nodes)+1),
+			"pubkey":       pubKey,
+			"user_address": usrAddr,
+			"priv_key":     privKey,
+			"notification": notif,
+		})
+	}
+	delete(doc, "pubkey")
+	delete(doc, "user_address")
+	delete(doc, "priv_key")
+	delete(doc, "notification")
+	return true
+}
+
+// ChatID из документа (в JSON это json.Number, в bson - int64)
+func rawChatID(doc map[string]interface{}) (int64, error) {
+	switch v := doc["chat_id"].(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("у записи нет chat_id: %v", doc["chat_id"])
+}
+
+// Разбор JSON записи в документ для миграций
+func decodeRaw(data []byte) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	return doc, err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Записи старых версий схемы
+func legacyDocs() []map[string]interface{} {
+	return []map[string]interface{}{
+		// версия 0: паблик-кей в pub_key (записан editUserKey), старый pubkey устарел
+		{
+			"chat_id":      int64(1),
+			"user_name":    "old",
+			"pubkey":       "MpOLD",
+			"pub_key":      "MpNEW",
+			"user_address": "Mx01",
+			"priv_key":     "enc:01",
+			"notification": true,
+		},
+		// мастернода удалена delNode: pub_key пустой
+		{
+			"chat_id":      int64(2),
+			"user_name":    "deleted",
+			"pubkey":       "MpOLD",
+			"pub_key":      "",
+			"notification": false,
+		},
+		// только pubkey, без pub_key
+		{
+			"chat_id":      int64(3),
+			"user_name":    "plain",
+			"pubkey":       "Mp03",
+			"notification": false,
+		},
+		// уже в новой схеме
+		{
+			"chat_id":   int64(4),
+			"user_name": "new",
+			"nodes":     []interface{}{map[string]interface{}{"label": "main", "pubkey": "Mp04", "notification": true}},
+		},
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	want := []usrData{
+		{ChatID: 1, UserName: "old", Nodes: []nodeData{{Label: "node1", PubKey: "MpNEW", UserAddress: "Mx01", PrivKey: "enc:01", Notification: true}}},
+		{ChatID: 2, UserName: "deleted", Nodes: nil},
+		{ChatID: 3, UserName: "plain", Nodes: []nodeData{{Label: "node1", PubKey: "Mp03"}}},
+		{ChatID: 4, UserName: "new", Nodes: []nodeData{{Label: "main", PubKey: "Mp04", Notification: true}}},
+	}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, doc := range legacyDocs() {
+				if err := store.SaveRaw(doc); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := runMigrations(store); err != nil {
+				t.Fatal(err)
+			}
+			if version, _ := store.SchemaVersion(); version != schemaVersion() {
+				t.Fatalf("SchemaVersion() = %d, want %d", version, schemaVersion())
+			}
+			usrs, err := store.LoadUsers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(usrs, func(i, j int) bool { return usrs[i].ChatID < usrs[j].ChatID })
+			if !reflect.DeepEqual(usrs, want) {
+				t.Fatalf("after migration:\n%+v\nwant:\n%+v", usrs, want)
+			}
+			docs, _ := store.LoadRaw()
+			for _, doc := range docs {
+				for _, field := range []string{"pub_key", "pubkey", "user_address", "priv_key", "notification"} {
+					if _, ok := doc[field]; ok {
+						t.Errorf("chat %v: legacy field %s left", doc["chat_id"], field)
+					}
+				}
+			}
+
+			// миграции не трогают уже изменённые записи
+			for _, doc := range docs {
+				for _, mgr := range migrations {
+					if mgr.Apply(doc) {
+						t.Errorf("chat %v: migration %d applied twice", doc["chat_id"], mgr.Version)
+					}
+				}
+			}
+
+			// повторный запуск ничего не меняет
+			if err := runMigrations(store); err != nil {
+				t.Fatal(err)
+			}
+			usrs2, _ := store.LoadUsers()
+			sort.Slice(usrs2, func(i, j int) bool { return usrs2[i].ChatID < usrs2[j].ChatID })
+			if !reflect.DeepEqual(usrs2, usrs) {
+				t.Fatalf("second run changed records:\n%+v\nwas:\n%+v", usrs2, usrs)
+			}
+			if version, _ := store.SchemaVersion(); version != schemaVersion() {
+				t.Fatalf("SchemaVersion() after second run = %d", version)
+			}
+		})
+	}
+}
+
+func TestRunMigrationsNewerSchema(t *testing.T) {
+	store := newMemoryStore()
+	store.SetSchemaVersion(schemaVersion() + 1)
+	if err := runMigrations(store); err == nil {
+		t.Fatal("runMigrations() on newer schema: want error")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Хранилище пользователей бота (MongoDB, MySQL, SQLite, Redis или память)
+type UserStore interface {
+	// Загрузка всех пользователей
+	LoadUsers() ([]usrData, error)
+	// Добавление нового пользователя
+	AddUser(usr usrData) error
+	// Перезапись данных пользователя (поиск по ChatID)
+	UpdateUser(usr usrData) error
+	// Очистка хранилища
+	Clean() error
+
+	// Записи пользователей как есть, для миграций схемы
+	LoadRaw() ([]map[string]interface{}, error)
+	SaveRaw(doc map[string]interface{}) error
+	// Версия схемы записей (0 - не записана)
+	SchemaVersion() (int, error)
+	SetSchemaVersion(version int) error
+
+	// Закрытие соединения
+	Close()
+}
+
+// Имя таблицы/коллекции/ключа пользователей бота
+const usrTableName = "tabl_bot_usr"
+
+// Имя таблицы/коллекции/ключа версии схемы
+const schemaTableName = "tabl_bot_schema"
+
+// Создание хранилища по типу из секции [database] INI файла
+func newUserStore(dbType string, dbAddress string) (UserStore, error) {
+	switch strings.ToLower(dbType) {
+	case "", "mongo", "mongodb":
+		return newMongoStore(dbAddress)
+	case "mysql":
+		return newSQLStore("mysql", dbAddress)
+	case "sqlite", "sqlite3":
+		return newSQLStore("sqlite3", dbAddress)
+	case "redis":
+		return newRedisStore(dbAddress)
+	case "memory":
+		return newMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("неизвестный тип базы данных: %s", dbType)
+}
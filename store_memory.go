@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Хранилище в памяти (данные теряются при перезапуске, для тестов). Как и SQL,
+// хранит записи JSON строкой, поэтому миграции схемы видят записи как есть
+type memoryStore struct {
+	mu      sync.Mutex
+	users   map[int64][]byte
+	version int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: map[int64][]byte{}}
+}
+
+func (s *memoryStore) LoadUsers() ([]usrData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usrs := []usrData{}
+	for _, data := range s.users {
+		var usr usrData
+		if err := json.Unmarshal(data, &usr); err != nil {
+			return usrs, err
+		}
+		usrs = append(usrs, usr)
+	}
+	return usrs, nil
+}
+
+func (s *memoryStore) AddUser(usr usrData) error {
+	return s.UpdateUser(usr)
+}
+
+func (s *memoryStore) UpdateUser(usr usrData) error {
+	data, err := json.Marshal(usr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.users[usr.ChatID] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Clean() error {
+	s.mu.Lock()
+	s.users = map[int64][]byte{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) LoadRaw() ([]map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := []map[string]interface{}{}
+	for _, data := range s.users {
+		doc, err := decodeRaw(data)
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (s *memoryStore) SaveRaw(doc map[string]interface{}) error {
+	chatID, err := rawChatID(doc)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.users[chatID] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) SchemaVersion() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, nil
+}
+
+func (s *memoryStore) SetSchemaVersion(version int) error {
+	s.mu.Lock()
+	s.version = version
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Close() {}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Хранилище в MongoDB
+type mongoStore struct {
+	session *mgo.Session
+}
+
+func newMongoStore(address string) (*mongoStore, error) {
+	session, err := mgo.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+	return &mongoStore{session: session}, nil
+}
+
+func (s *mongoStore) collection() *mgo.Collection {
+	return s.session.DB("mvc_db").C(usrTableName)
+}
+
+func (s *mongoStore) LoadUsers() ([]usrData, error) {
+	usrs := []usrData{}
+	err := s.collection().Find(bson.M{}).All(&usrs)
+	return usrs, err
+}
+
+func (s *mongoStore) AddUser(usr usrData) error {
+	return s.collection().Insert(usr)
+}
+
+func (s *mongoStore) UpdateUser(usr usrData) error {
+	return s.collection().Update(bson.M{"chat_id": usr.ChatID}, usr)
+}
+
+func (s *mongoStore) Clean() error {
+	_, err := s.collection().RemoveAll(bson.M{})
+	return err
+}
+
+func (s *mongoStore) LoadRaw() ([]map[string]interface{}, error) {
+	docs := []bson.M{}
+	err := s.collection().Find(bson.M{}).All(&docs)
+	retDocs := []map[string]interface{}{}
+	for _, doc := range docs {
+		retDocs = append(retDocs, map[string]interface{}(doc))
+	}
+	return retDocs, err
+}
+
+func (s *mongoStore) SaveRaw(doc map[string]interface{}) error {
+	return s.collection().UpdateId(doc["_id"], bson.M(doc))
+}
+
+func (s *mongoStore) SchemaVersion() (int, error) {
+	var res struct {
+		Version int `bson:"version"`
+	}
+	err := s.session.DB("mvc_db").C(schemaTableName).FindId(usrTableName).One(&res)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return res.Version, err
+}
+
+func (s *mongoStore) SetSchemaVersion(version int) error {
+	_, err := s.session.DB("mvc_db").C(schemaTableName).UpsertId(usrTableName, bson.M{"version": version})
+	return err
+}
+
+func (s *mongoStore) Close() {
+	s.session.Close()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// Хранилище в Redis: один хэш, поле - ChatID, значение - JSON пользователя
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(address string) (*redisStore, error) {
+	client := redis.NewClient(&redis.Options{Addr: address})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisStore{client: client}, nil
+}
+
+func (s *redisStore) LoadUsers() ([]usrData, error) {
+	usrs := []usrData{}
+	all, err := s.client.HGetAll(usrTableName).Result()
+	if err != nil {
+		return usrs, err
+	}
+	for _, data := range all {
+		var usr usrData
+		if err := json.Unmarshal([]byte(data), &usr); err != nil {
+			return usrs, err
+		}
+		usrs = append(usrs, usr)
+	}
+	return usrs, nil
+}
+
+func (s *redisStore) AddUser(usr usrData) error {
+	return s.save(usr)
+}
+
+func (s *redisStore) UpdateUser(usr usrData) error {
+	return s.save(usr)
+}
+
+func (s *redisStore) save(usr usrData) error {
+	data, err := json.Marshal(usr)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(usrTableName, strconv.FormatInt(usr.ChatID, 10), data).Err()
+}
+
+func (s *redisStore) Clean() error {
+	return s.client.Del(usrTableName).Err()
+}
+
+func (s *redisStore) LoadRaw() ([]map[string]interface{}, error) {
+	docs := []map[string]interface{}{}
+	all, err := s.client.HGetAll(usrTableName).Result()
+	if err != nil {
+		return docs, err
+	}
+	for _, data := range all {
+		doc, err := decodeRaw([]byte(data))
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (s *redisStore) SaveRaw(doc map[string]interface{}) error {
+	chatID, err := rawChatID(doc)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(usrTableName, strconv.FormatInt(chatID, 10), data).Err()
+}
+
+func (s *redisStore) SchemaVersion() (int, error) {
+	version, err := s.client.HGet(schemaTableName, usrTableName).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (s *redisStore) SetSchemaVersion(version int) error {
+	return s.client.HSet(schemaTableName, usrTableName, version).Err()
+}
+
+func (s *redisStore) Close() {
+	s.client.Close()
+}
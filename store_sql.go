@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Хранилище в SQL базе (MySQL или SQLite). Данные пользователя лежат
+// одной JSON строкой, чтобы не менять таблицу при каждом новом поле.
+type sqlStore struct {
+	db *sql.DB
+}
+
+func newSQLStore(driver string, dsn string) (*sqlStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + usrTableName + " (chat_id BIGINT PRIMARY KEY, user_name VARCHAR(64), data TEXT)")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + schemaTableName + " (name VARCHAR(64) PRIMARY KEY, version INT)")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{db: db}, nil
+}
+
+func (s *sqlStore) LoadUsers() ([]usrData, error) {
+	usrs := []usrData{}
+	rows, err := s.db.Query("SELECT data FROM " + usrTableName)
+	if err != nil {
+		return usrs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return usrs, err
+		}
+		var usr usrData
+		if err := json.Unmarshal([]byte(data), &usr); err != nil {
+			return usrs, err
+		}
+		usrs = append(usrs, usr)
+	}
+	return usrs, rows.Err()
+}
+
+func (s *sqlStore) AddUser(usr usrData) error {
+	return s.save(usr)
+}
+
+func (s *sqlStore) UpdateUser(usr usrData) error {
+	return s.save(usr)
+}
+
+// REPLACE INTO понимают и MySQL и SQLite
+func (s *sqlStore) save(usr usrData) error {
+	data, err := json.Marshal(usr)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("REPLACE INTO "+usrTableName+" (chat_id, user_name, data) VALUES (?, ?, ?)", usr.ChatID, usr.UserName, string(data))
+	return err
+}
+
+func (s *sqlStore) Clean() error {
+	_, err := s.db.Exec("DELETE FROM " + usrTableName)
+	return err
+}
+
+func (s *sqlStore) LoadRaw() ([]map[string]interface{}, error) {
+	docs := []map[string]interface{}{}
+	rows, err := s.db.Query("SELECT data FROM " + usrTableName)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return docs, err
+		}
+		doc, err := decodeRaw([]byte(data))
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func (s *sqlStore) SaveRaw(doc map[string]interface{}) error {
+	chatID, err := rawChatID(doc)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	userName, _ := doc["user_name"].(string)
+	_, err = s.db.Exec("REPLACE INTO "+usrTableName+" (chat_id, user_name, data) VALUES (?, ?, ?)", chatID, userName, string(data))
+	return err
+}
+
+func (s *sqlStore) SchemaVersion() (int, error) {
+	version := 0
+	err := s.db.QueryRow("SELECT version FROM "+schemaTableName+" WHERE name = ?", usrTableName).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (s *sqlStore) SetSchemaVersion(version int) error {
+	_, err := s.db.Exec("REPLACE INTO "+schemaTableName+" (name, version) VALUES (?, ?)", usrTableName, version)
+	return err
+}
+
+func (s *sqlStore) Close() {
+	s.db.Close()
+}
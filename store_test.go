@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Хранилища, которые можно поднять без внешних сервисов
+func testStores(t *testing.T) map[string]UserStore {
+	sqlite, err := newSQLStore("sqlite3", filepath.Join(t.TempDir(), "tbot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sqlite.Close)
+	return map[string]UserStore{
+		"memory": newMemoryStore(),
+		"sqlite": sqlite,
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	usr1 := usrData{
+		ChatID:   101,
+		UserName: "alice",
+		Lang:     "en",
+		Nodes: []nodeData{{
+			Label:        "node1",
+			PubKey:       "Mpaa",
+			UserAddress:  "Mxbb",
+			PrivKey:      "enc:cc",
+			Notification: true,
+			MissedLevels: []int{3, 6},
+		}},
+	}
+	usr2 := usrData{ChatID: 202, UserName: "bob"}
+	usr2upd := usrData{ChatID: 202, UserName: "bob", Nodes: []nodeData{{Label: "node1", PubKey: "Mpdd"}}}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.AddUser(usr1); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.AddUser(usr2); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpdateUser(usr2upd); err != nil {
+				t.Fatal(err)
+			}
+
+			usrs, err := store.LoadUsers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(usrs, func(i, j int) bool { return usrs[i].ChatID < usrs[j].ChatID })
+			if want := []usrData{usr1, usr2upd}; !reflect.DeepEqual(usrs, want) {
+				t.Fatalf("LoadUsers() = %+v, want %+v", usrs, want)
+			}
+
+			if err := store.SetSchemaVersion(2); err != nil {
+				t.Fatal(err)
+			}
+			if version, err := store.SchemaVersion(); err != nil || version != 2 {
+				t.Fatalf("SchemaVersion() = %d, %v, want 2", version, err)
+			}
+
+			if err := store.Clean(); err != nil {
+				t.Fatal(err)
+			}
+			if usrs, err := store.LoadUsers(); err != nil || len(usrs) != 0 {
+				t.Fatalf("LoadUsers() after Clean = %+v, %v, want empty", usrs, err)
+			}
+		})
+	}
+}
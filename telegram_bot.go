@@ -25,53 +25,63 @@ SOFTWARE.
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-telegram-bot-api/telegram-bot-api"
 	"gopkg.in/ini.v1"
-	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
 
 	m "github.com/ValidatorCenter/minter-go-sdk"
 )
 
 // пока данные будем хранить в памяти
 var (
-	CoinMinter string // Основная монета Minter
-	allValid   []candidate_info
-	allUser    []usrData
-	MnAddress  string // MasterNode
-	//MnAddressReserv string // резервный
-	TgTokenAPI   string // Токен к API телеграма
-	TgTimeUpdate int64  // Время в сек. обновления статуса
-	DBAddress    string // MongoDB
-	HelpMsg      = "Это простой мониторинг доступности мастерноды валидатора и краткая информация о ней.\n" +
-		"Список доступных комманд:\n" +
-		"/node_info - информация о мастерноде привязанной к пользователю\n" +
-		"/node_info [часть-pubkey] - информация о мастернодах найденных по части указанного ключа\n" +
-		"/node_add [pubkey] - добавление мастерноды для мониторинга состояния и привязка её к пользователю\n" +
-		"/node_edit [pubkey] - изменение мастерноды для мониторинга привязанной к пользователю\n" +
-		"/node_del - удаление мастерноды из мониторинга и очитска данных\n" +
-		"/candidate [on/off/1/0] - включить или отключить мастерноду (!-только если привязан PrivKey)\n" +
-		"/notification - вкл/откл уведомление об исключение мастерноды из списка валидаторов\n" +
-		"/start - отобразить это сообщение\n" +
-		"/help - отобразить это сообщение\n\n" +
-		"Начните с привязки мастерноды для мониторинга!"
+	CoinMinter   string              // Основная монета Minter
+	allCand      = newCandRegistry() // кандидаты и валидаторы
+	allUser      = newUserRegistry() // пользователи
+	saveMutex    sync.Mutex          // порядок записи пользователей в БД
+	MnAddress    string              // MasterNode (выбранная из MnAddresses)
+	TgTokenAPI   string              // Токен к API телеграма
+	TgTimeUpdate int64               // Время в сек. обновления статуса
+	DBType       string              // Тип БД: mongodb, mysql, sqlite, redis, memory
+	DBAddress    string              // Адрес БД (для sqlite - путь к файлу)
+	KeyFileName  string              // Файл мастер-ключа шифрования приватных ключей
 )
 
 // Структура данных пользователя
 type usrData struct {
-	ChatID       int64  `bson:"chat_id"`
-	UserName     string `bson:"user_name"`
-	UserAddress  string `bson:"user_address"`
-	PubKey       string `bson:"pubkey"`
-	PrivKey      string `bson:"priv_key"`
-	Notification bool   `bson:"notification"`
+	ChatID   int64       `json:"chat_id" bson:"chat_id"`
+	UserName string      `json:"user_name" bson:"user_name"`
+	Nodes    []nodeData  `json:"nodes" bson:"nodes"`
+	Lang     string      `json:"lang" bson:"lang"`   // язык сообщений бота ("" - по умолчанию)
+	Watch    []watchData `json:"watch" bson:"watch"` // подписки на чужие мастерноды (/watch)
+}
+
+// Структура мастерноды пользователя
+type nodeData struct {
+	Label        string `json:"label" bson:"label"`
+	PubKey       string `json:"pubkey" bson:"pubkey"`
+	UserAddress  string `json:"user_address" bson:"user_address"`
+	PrivKey      string `json:"priv_key" bson:"priv_key"`
+	Notification bool   `json:"notification" bson:"notification"`
+	MissedLevels []int  `json:"missed_levels" bson:"missed_levels"` // пороги оповещения о пропуске блоков
+	// Автоотключение мастерноды при пропуске блоков (!-только если привязан PrivKey)
+	AutoOff   bool      `json:"auto_off" bson:"auto_off"`
+	AutoOffTx string    `json:"auto_off_tx" bson:"auto_off_tx"` // последняя транзакция автоотключения
+	AutoOffAt time.Time `json:"auto_off_at" bson:"auto_off_at"`
+	// Пороги оповещения о месте по стэку и запасе стэка над последним местом валидатора, % (0 - по умолчанию)
+	RankLimit   int     `json:"rank_limit" bson:"rank_limit"`
+	StakeMargin float32 `json:"stake_margin" bson:"stake_margin"`
+}
+
+// Подписка на мастерноду без владения ей: только оповещения
+type watchData struct {
+	PubKey string   `json:"pubkey" bson:"pubkey"`
+	Alerts []string `json:"alerts" bson:"alerts"` // типы оповещений (watchAlerts)
 }
 
 // структура кандидата/валидатора
@@ -85,7 +95,6 @@ type candidate_info struct {
 	//Stakes           []stakes_info `json:"stakes" bson:"stakes" gorm:"stakes"` // Только у: Candidate(по PubKey)
 }
 
-/*
 // стэк делегатов
 type stakes_info struct {
 	Owner      string  `json:"owner" bson:"owner"`
@@ -94,15 +103,36 @@ type stakes_info struct {
 	BipValue   string  `json:"bip_value" bson:"bip_value"`
 	Value32    float32 `bson:"value32"`
 	BipValue32 float32 `bson:"bip_value32"`
-}*/
+}
 
 // Статус мастерноды
-func getNodeStatusString(statInt int) string {
-	statStr := "Кандидат"
-	if statInt == 2 {
-		statStr = "Валидатор"
+func getNodeStatusString(lang string, cnd candidate_info) string {
+	if cnd.PubKey == "" {
+		return tr(lang, "status_not_found")
+	}
+	if getStatusValid(cnd.PubKey) {
+		return tr(lang, "status_validator")
 	}
-	return statStr
+	return getCandStatusString(lang, cnd.StatusInt)
+}
+
+// Статус кандидата только по числовому значению, без списка валидаторов
+func getCandStatusString(lang string, statusInt int) string {
+	if statusInt == 2 {
+		return tr(lang, "status_online")
+	}
+	return tr(lang, "status_offline")
+}
+
+// Разбор аргумента вкл/откл: on/off/1/0, второе значение false - если формат неверный
+func parseOnOff(argument string) (bool, bool) {
+	switch strings.ToLower(argument) {
+	case "1", "on":
+		return true, true
+	case "0", "off":
+		return false, true
+	}
+	return false, false
 }
 
 // Сокращение строки
@@ -114,166 +144,298 @@ func getMinString(bigStr string) string {
 	}
 }
 
+// Краткая информация о мастерноде пользователя
+func getNodeInfoString(lang string, oNd nodeData) string {
+	cndI := getValidInfo(oNd.PubKey)
+	chekIt := tr(lang, "no")
+	if oNd.Notification == true {
+		chekIt = tr(lang, "yes")
+	}
+	autoOff := tr(lang, "no")
+	if oNd.AutoOff == true {
+		autoOff = tr(lang, "yes")
+	}
+	privKey := tr(lang, "no")
+	if isEncryptedKey(oNd.PrivKey) {
+		privKey = tr(lang, "privkey_encrypted")
+	} else if oNd.PrivKey != "" {
+		privKey = tr(lang, "privkey_plain")
+	}
+	missed, wnd := getMissedBlocks(oNd.PubKey)
+	retTxt := tr(lang, "node_info",
+		oNd.Label,
+		getMinString(oNd.PubKey),
+		getMinString(oNd.UserAddress),
+		privKey,
+		getNodeStatusString(lang, cndI),
+		cndI.Commission,
+		cndI.TotalStake,
+		missed, wnd,
+		chekIt,
+		autoOff)
+	if validUpdated, validStale := allCand.Updated(); validStale {
+		retTxt += tr(lang, "node_info_stale", validUpdated.Format("02.01.2006 15:04:05"))
+	}
+	if oNd.AutoOffTx != "" {
+		retTxt += tr(lang, "node_info_autooff", oNd.AutoOffAt.Format("02.01.2006 15:04"), getMinString(oNd.AutoOffTx))
+	}
+	return retTxt
+}
+
 // Загрузка пользователей из БД в память
-func loadAllUsers(session *mgo.Session) {
-	// Таблица всех кандидатов candidate_info
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	qUsr := bson.M{}
-	usrCollection.Find(qUsr).All(&allUser)
+func loadAllUsers(store UserStore) {
+	usrs, err := store.LoadUsers()
+	if err != nil {
+		fmt.Println("ERROR", err)
+	}
+	allUser.Load(usrs)
 }
 
 // Добавляем нового пользователя в БД и в память
-func addUser(session *mgo.Session, usr1 usrData) {
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	err := usrCollection.Insert(usr1)
+func addUser(store UserStore, usr1 usrData) {
+	err := store.AddUser(usr1)
 	if err != nil {
 		fmt.Println("ERROR", err)
 	}
 	//FIXME: но! пока всёравно добавим в память
-	allUser = append(allUser, usr1)
+	allUser.Add(usr1)
 }
 
 // Очистка базы (root)
-func cleanDB(session *mgo.Session) {
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	_, err := usrCollection.RemoveAll(bson.M{})
+func cleanDB(store UserStore) {
+	err := store.Clean()
 	if err != nil {
 		fmt.Println(err)
 	}
 	fmt.Println("очищена - BD")
 
 	// очищаем
-	allValid = allValid[:0]
+	allCand.Reset()
+	allUser.Load(nil)
 }
 
-// Изменение PubKey и PrivKey мастерноды пользователя в БД и в память
-func editUserKey(session *mgo.Session, usr1 usrData) {
-	var err error
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	if usr1.PubKey != "" && usr1.PrivKey == "" {
-		err = usrCollection.Update(bson.M{"chat_id": usr1.ChatID}, bson.M{"$set": bson.M{"pub_key": usr1.PubKey}})
-	} else if usr1.PubKey != "" && usr1.PrivKey != "" {
-		err = usrCollection.Update(bson.M{"chat_id": usr1.ChatID}, bson.M{"$set": bson.M{"pub_key": usr1.PubKey, "user_address": usr1.UserAddress, "priv_key": usr1.PrivKey}})
-	} else {
-		fmt.Println("ERROR", "Что-то пошло не так с изменением _Ключей_")
+// Изменение пользователя в памяти и сохранение его в БД. Запись в БД идёт
+// под saveMutex, чтобы более старая копия не перезаписала более новую
+func editUser(store UserStore, chatID int64, fn func(usr *usrData)) {
+	saveMutex.Lock()
+	defer saveMutex.Unlock()
+	oneUsr, ok := allUser.Update(chatID, fn)
+	if !ok {
 		return
 	}
+	err := store.UpdateUser(oneUsr)
 	if err != nil {
 		fmt.Println("ERROR", err)
 	}
-	//FIXME: но! пока всё-равно добавим в память
-	for iU, _ := range allUser {
-		if allUser[iU].ChatID == usr1.ChatID {
-			allUser[iU].PubKey = usr1.PubKey
-			allUser[iU].PrivKey = usr1.PrivKey
-			allUser[iU].UserAddress = usr1.UserAddress
+}
+
+// Метка для новой мастерноды пользователя: node1, node2...
+func newNodeLabel(usr usrData) string {
+	for i := len(usr.Nodes) + 1; ; i++ {
+		lbl := fmt.Sprintf("node%d", i)
+		if findNode(usr, lbl) == -1 {
+			return lbl
+		}
+	}
+}
+
+// Поиск мастерноды пользователя по метке или паблик-кею, -1 если не найдена
+func findNode(usr usrData, key string) int {
+	for iN, oneNode := range usr.Nodes {
+		if oneNode.Label == key || oneNode.PubKey == key {
+			return iN
 		}
 	}
+	return -1
 }
 
-// Удаление данных о мастерноде
-func delNode(session *mgo.Session, chatID int64) {
-	var err error
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	err = usrCollection.Update(bson.M{"chat_id": chatID}, bson.M{"$set": bson.M{"pub_key": "", "priv_key": "", "notification": false}})
+// Добавление мастерноды пользователю в БД и в память (пользователь создаётся при необходимости)
+func addNode(store UserStore, chatID int64, userName string, lang string, node1 nodeData) {
+	if _, ok := allUser.Get(chatID); ok {
+		editUser(store, chatID, func(usr *usrData) {
+			if node1.Label == "" {
+				node1.Label = newNodeLabel(*usr)
+			}
+			usr.Nodes = append(usr.Nodes, node1)
+		})
+		return
+	}
+	if node1.Label == "" {
+		node1.Label = "node1"
+	}
+	addUser(store, usrData{ChatID: chatID, UserName: userName, Lang: lang, Nodes: []nodeData{node1}})
+}
 
-	if err != nil {
-		fmt.Println("ERROR", err)
+// Изменение PubKey и PrivKey мастерноды пользователя в БД и в память
+func editUserKey(store UserStore, chatID int64, idxNode int, node1 nodeData) {
+	if node1.PubKey == "" {
+		fmt.Println("ERROR", "Что-то пошло не так с изменением _Ключей_")
+		return
 	}
-	//FIXME: но! пока всё-равно добавим в память
-	for iU, _ := range allUser {
-		if allUser[iU].ChatID == chatID {
-			allUser[iU].PubKey = ""
-			allUser[iU].PrivKey = ""
-			allUser[iU].Notification = false
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode < len(usr.Nodes) {
+			usr.Nodes[idxNode].PubKey = node1.PubKey
+			if node1.PrivKey != "" {
+				usr.Nodes[idxNode].PrivKey = node1.PrivKey
+				usr.Nodes[idxNode].UserAddress = node1.UserAddress
+			}
 		}
-	}
+	})
 }
 
-// Изменение PubKey мастерноды пользователя в БД и в память
-func editNodeNotif(session *mgo.Session, ChatID int64) string {
+// Удаление мастерноды пользователя
+func delNode(store UserStore, chatID int64, idxNode int) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode < len(usr.Nodes) {
+			usr.Nodes = append(usr.Nodes[:idxNode], usr.Nodes[idxNode+1:]...)
+		}
+	})
+}
+
+// Изменение статуса уведомлений мастерноды (idxNode = -1 - всех мастернод) в БД и в память
+func editNodeNotif(store UserStore, chatID int64, lang string, idxNode int) string {
 	nowStatus := false
 	retTxt := ""
-	for iU, _ := range allUser {
-		if allUser[iU].ChatID == ChatID {
-			nowStatus = allUser[iU].Notification
+	oneUsr, _ := allUser.Get(chatID)
+	for iN, oneNode := range oneUsr.Nodes {
+		if (idxNode == -1 || idxNode == iN) && oneNode.Notification == true {
+			nowStatus = true
 		}
 	}
 	// Меняем статус
 	if nowStatus == true {
 		nowStatus = false
-		retTxt = "Отключено уведомление об исключение мастерноды из Валидаторов"
+		retTxt = tr(lang, "notif_off")
 	} else {
 		nowStatus = true
-		retTxt = "Включено уведомление об исключение мастерноды из Валидаторов"
+		retTxt = tr(lang, "notif_on")
 	}
 
-	usrCollection := session.DB("mvc_db").C("tabl_bot_usr")
-	err := usrCollection.Update(bson.M{"chat_id": ChatID}, bson.M{"$set": bson.M{"notification": nowStatus}})
-	if err != nil {
-		fmt.Println("ERROR", err)
+	editUser(store, chatID, func(usr *usrData) {
+		for iN, _ := range usr.Nodes {
+			if idxNode == -1 || idxNode == iN {
+				usr.Nodes[iN].Notification = nowStatus
+			}
+		}
+	})
+	return retTxt
+}
+
+// Изменение языка пользователя в БД и в память (пользователь создаётся при необходимости)
+func editUserLang(store UserStore, chatID int64, userName string, lang string) {
+	if _, ok := allUser.Get(chatID); ok {
+		editUser(store, chatID, func(usr *usrData) {
+			usr.Lang = lang
+		})
+		return
 	}
-	//FIXME: но! пока всёравно добавим в память
-	for iU, _ := range allUser {
-		if allUser[iU].ChatID == ChatID {
-			allUser[iU].Notification = nowStatus
+	addUser(store, usrData{ChatID: chatID, UserName: userName, Lang: lang})
+}
+
+// Изменение порогов пропуска блоков мастерноды в БД и в память
+func editNodeMissed(store UserStore, chatID int64, idxNode int, levels []int) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode < len(usr.Nodes) {
+			usr.Nodes[idxNode].MissedLevels = levels
+		}
+	})
+}
+
+// Изменение порогов оповещения о месте и запасе стэка мастерноды в БД и в память
+func editNodeRank(store UserStore, chatID int64, idxNode int, rankLimit int, stakeMargin float32) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode < len(usr.Nodes) {
+			usr.Nodes[idxNode].RankLimit = rankLimit
+			usr.Nodes[idxNode].StakeMargin = stakeMargin
 		}
+	})
+}
+
+// Подписка на мастерноду или изменение типов оповещений в БД и в память (пользователь создаётся при необходимости)
+func editUserWatch(store UserStore, chatID int64, userName string, lang string, watch1 watchData) {
+	if _, ok := allUser.Get(chatID); ok {
+		editUser(store, chatID, func(usr *usrData) {
+			if iW := findWatch(*usr, watch1.PubKey); iW != -1 {
+				usr.Watch[iW] = watch1
+			} else {
+				usr.Watch = append(usr.Watch, watch1)
+			}
+		})
+		return
 	}
-	return retTxt
+	addUser(store, usrData{ChatID: chatID, UserName: userName, Lang: lang, Watch: []watchData{watch1}})
 }
 
-// Возвращает список валидаторов в память
-func ReturnValid() {
-	// очищаем
-	allValid = allValid[:0]
+// Удаление подписки на мастерноду в БД и в память (idxWatch = -1 - всех подписок)
+func delUserWatch(store UserStore, chatID int64, idxWatch int) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxWatch == -1 {
+			usr.Watch = nil
+		} else if idxWatch < len(usr.Watch) {
+			usr.Watch = append(usr.Watch[:idxWatch], usr.Watch[idxWatch+1:]...)
+		}
+	})
+}
 
+// Возвращает список валидаторов и кандидатов в память. Списки заменяются целиком
+// и только если все данные получены, иначе остаются прошлые и возвращается false
+func ReturnValid() bool {
 	sdk := m.SDK{
-		MnAddress: MnAddress,
+		MnAddress: getMnAddress(),
 	}
 
-	vldr, err := sdk.GetValidators()
+	var vldr []string
+	err := mnCall(sdk.MnAddress, func() error {
+		res, err := sdk.GetValidators()
+		for _, onePubKey := range res {
+			vldr = append(vldr, onePubKey.PubKey)
+		}
+		return err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		nodeError(err)
+		return false
+	}
+	// пустого списка валидаторов в рабочей сети не бывает
+	if len(vldr) == 0 {
+		fmt.Println("ERROR", "Мастернода вернула пустой список валидаторов")
+		return false
 	}
-	for _, onePubKey := range vldr {
-		cnd, err := sdk.GetCandidate(onePubKey.PubKey)
-		if err != nil {
-			fmt.Println(err.Error())
-			return
-		}
-		// FIXME: не красивое решение+++
-		body, err := json.Marshal(cnd)
-		if err != nil {
-			fmt.Println(err.Error())
-			return
-		}
-		var data candidate_info
-		json.Unmarshal(body, &data)
 
-    fmt.Printf("CND::%#v\n", cnd)   // TODO: скрыть
-		fmt.Printf("DATA::%#v\n", data) // TODO: скрыть
+	newCand, fullList, err := fetchCandidates(&sdk, vldr, allUser.PubKeys())
+	if err != nil {
+		nodeError(err)
+		return false
+	}
+	inValid := map[string]bool{}
+	for _, pubKey := range vldr {
+		inValid[pubKey] = true
+	}
+	newValid := []candidate_info{}
+	for _, oneNode := range newCand {
+		if inValid[oneNode.PubKey] {
+			newValid = append(newValid, oneNode)
+		}
+	}
 
-		allValid = append(allValid, data)
+	if !fullList {
+		fmt.Println("ERROR", "Полный список кандидатов не получен, в памяти только валидаторы и отслеживаемые мастерноды")
 	}
+	allCand.Set(newValid, newCand, !fullList, time.Now())
+	return true
 }
 
-// Получаем данные валидатора по его паблик-кею
+// Получаем данные кандидата (валидатора) по его паблик-кею
 func getValidInfo(pubKey string) candidate_info {
-	var retVld candidate_info
-	for _, oneNode := range allValid {
-		if oneNode.PubKey == pubKey {
-			return oneNode
-		}
-	}
+	retVld, _ := allCand.Get(pubKey)
 	return retVld
 }
 
-// поиск валидатора по его части паблик-кею или названию
+// поиск кандидата (валидатора) по его части паблик-кею или названию
 func searchValid(search string) []candidate_info {
 	srchUpper := strings.ToUpper(search)
 	retVld := []candidate_info{}
-	for _, oneNode := range allValid {
+	for _, oneNode := range allCand.All() {
 		pkUpper := strings.ToUpper(oneNode.PubKey)
 		if strings.Contains(pkUpper, srchUpper) == true {
 			retVld = append(retVld, oneNode)
@@ -282,37 +444,49 @@ func searchValid(search string) []candidate_info {
 	return retVld
 }
 
-// Получаем данные по пользователю по его ID чата (или еще по нику?)
-func getUser(chatID int64) usrData {
-	for _, oneUsr := range allUser {
-		if oneUsr.ChatID == chatID {
-			return oneUsr
+// Язык чата: сохранённый у пользователя, иначе из настроек Telegram. Язык из Telegram
+// сохраняется пользователю, чтобы оповещения monitor приходили на том же языке
+func chatLang(store UserStore, chatID int64, from *tgbotapi.User) string {
+	oUsr, ok := allUser.Get(chatID)
+	if ok && oUsr.Lang != "" {
+		return userLang(oUsr)
+	}
+	if from != nil {
+		if tgLang := normLang(from.LanguageCode); tgLang != "" {
+			if ok {
+				editUser(store, chatID, func(usr *usrData) {
+					if usr.Lang == "" {
+						usr.Lang = tgLang
+					}
+				})
+			}
+			return tgLang
 		}
 	}
-	return usrData{}
+	return LangDefault
+}
+
+// Получаем данные по пользователю по его ID чата (или еще по нику?)
+func getUser(chatID int64) usrData {
+	oneUsr, _ := allUser.Get(chatID)
+	return oneUsr
 }
 
 // Мастернода в списке валидаторов? проверка по паблик-кею
 func getStatusValid(pubKey string) bool {
-	for _, oneNode := range allValid {
-		if oneNode.PubKey == pubKey {
-			// Мастернода в списке, но может-быть статус не валидатора
-			if oneNode.StatusInt == 2 {
-				return true
-			} else {
-				return false
-			}
-		}
+	if !allCand.InValid(pubKey) {
+		return false
 	}
-	return false
+	// Мастернода в списке, но может-быть статус не валидатора
+	oneNode, _ := allCand.Get(pubKey)
+	return oneNode.StatusInt == 2
 }
 
 // Функция транзакции вкл/откл мастерноды
 func SetCandidateTransaction(usrAddr string, keyString string, pubKeyMN string, status bool) (string, error) {
-	sdk := m.SDK{
-		MnAddress:     MnAddress,
-		AccAddress:    usrAddr,
-		AccPrivateKey: keyString,
+	sdk, err := newSignSDK(usrAddr, keyString)
+	if err != nil {
+		return "", err
 	}
 
 	sndDt := m.TxSetCandidateData{
@@ -331,19 +505,75 @@ func SetCandidateTransaction(usrAddr string, keyString string, pubKeyMN string,
 }
 
 // Сам мониторинг! как горутина!
-func monitor(bot *tgbotapi.BotAPI) {
+func monitor(bot *tgbotapi.BotAPI, store UserStore) {
 	// бесконечный цикл
 	for {
-		ReturnValid()
-
-		for _, oneUser := range allUser {
-			if !getStatusValid(oneUser.PubKey) && oneUser.Notification == true {
-				//Алам!
-				fmt.Println("NOOOOO! ", oneUser.UserName)
-				// отправляем пользователю сообщение
-				msg := tgbotapi.NewMessage(oneUser.ChatID, "Нода не в валидаторах!")
-				bot.Send(msg)
+		now := time.Now()
+
+		// без рабочей мастерноды данные недостоверны
+		dataOk := selectMasternode(bot)
+		if dataOk {
+			pollStart := time.Now()
+			dataOk = ReturnValid()
+			mtrPollDuration.Observe(time.Since(pollStart).Seconds())
+			ReturnBlocks()
+			updateMetrics()
+		}
+		checkDegraded(bot, dataOk, now)
+
+		// по устаревшим данным пользователей не оповещаем
+		if dataOk {
+			ranks := getStakeRanks()
+			changes := checkNodeChanges()
+			for _, oneUser := range allUser.Snapshot() {
+				lang := userLang(oneUser)
+				for _, oneNode := range oneUser.Nodes {
+					alrtTxt := checkNodeAlert(oneUser.ChatID, lang, oneNode, getStatusValid(oneNode.PubKey), now)
+					if alrtTxt != "" {
+						//Алам!
+						fmt.Println("NOOOOO! ", oneUser.UserName, alrtTxt)
+						// отправляем пользователю сообщение
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+					alrtTxt = checkMissedAlert(oneUser.ChatID, lang, oneNode)
+					if alrtTxt != "" {
+						fmt.Println("MISSED! ", oneUser.UserName, alrtTxt)
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+					alrtTxt = checkRankAlert(oneUser.ChatID, lang, oneNode, ranks)
+					if alrtTxt != "" {
+						fmt.Println("RANK! ", oneUser.UserName, alrtTxt)
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+					if chng, ok := changes[oneNode.PubKey]; ok && oneNode.Notification {
+						alrtTxt = getChangeString(lang, oneNode.Label, chng)
+						fmt.Println("CHANGE! ", oneUser.UserName, alrtTxt)
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+					alrtTxt = checkAutoOff(bot, store, oneUser.ChatID, lang, oneNode, now)
+					if alrtTxt != "" {
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+				}
+				// подписки: своя мастернода уже проверена выше
+				for _, oneWatch := range oneUser.Watch {
+					if findNode(oneUser, oneWatch.PubKey) != -1 {
+						continue
+					}
+					alrtTxt := checkWatchAlert(oneUser.ChatID, lang, oneWatch, changes, now)
+					if alrtTxt != "" {
+						fmt.Println("WATCH! ", oneUser.UserName, alrtTxt)
+						msg := tgbotapi.NewMessage(oneUser.ChatID, alrtTxt)
+						sendMessage(bot, msg)
+					}
+				}
 			}
+			cleanNodeAlerts()
 		}
 
 		fmt.Printf("Пауза %dсек.... в этот момент лучше прерывать\n", TgTimeUpdate)
@@ -355,9 +585,14 @@ func main() {
 	ConfFileName := "cmc0.ini"
 
 	// проверяем есть ли входной параметр/аргумент
-	if len(os.Args) == 2 {
+	if len(os.Args) >= 2 {
 		ConfFileName = os.Args[1]
 	}
+	// вторым аргументом может быть команда: migrate_keys - зашифровать приватные ключи в БД
+	runCommand := ""
+	if len(os.Args) >= 3 {
+		runCommand = os.Args[2]
+	}
 	fmt.Printf("INI=%s\n", ConfFileName)
 
 	// INI
@@ -368,10 +603,27 @@ func main() {
 	} else {
 		fmt.Println("...данные с INI файла = загружены!")
 	}
+	secLang := cfg.Section("lang")
+	LangDir = secLang.Key("DIR").MustString(LangDir)
+	LangDefault = secLang.Key("DEFAULT").MustString(LangDefault)
+	err = loadCatalogs(LangDir)
+	if err != nil {
+		fmt.Println("Ошибка загрузки каталогов сообщений:", err.Error())
+		return
+	}
 	secMN := cfg.Section("masternode")
 	MnAddress = secMN.Key("ADDRESS").String()
-	//MnAddressReserv = secMN.Key("ADDRESS_2").String()
+	MnAddresses = []string{MnAddress}
+	for i := 2; secMN.HasKey(fmt.Sprintf("ADDRESS_%d", i)); i++ {
+		MnAddresses = append(MnAddresses, secMN.Key(fmt.Sprintf("ADDRESS_%d", i)).String())
+	}
+	MnMaxLag = secMN.Key("MAX_LAG").MustInt(MnMaxLag)
+	MnWorkers = secMN.Key("WORKERS").MustInt(MnWorkers)
+	MnRate = secMN.Key("RATE").MustInt(MnRate)
+	MnTimeout = secMN.Key("TIMEOUT").MustInt(MnTimeout)
+	setMnTimeout()
 	secDB := cfg.Section("database")
+	DBType = secDB.Key("TYPE").String()
 	DBAddress = secDB.Key("ADDRESS").String()
 	netMN := cfg.Section("network")
 	CoinMinter = netMN.Key("COINNET").String()
@@ -383,259 +635,563 @@ func main() {
 		TgTimeUpdate = 60
 	}
 	TgTimeUpdate = int64(_TgTimeUpdate)
+	TgMode = secTG.Key("MODE").String()
+	TgWebhookURL = secTG.Key("WEBHOOK_URL").String()
+	TgListen = secTG.Key("LISTEN").String()
+	TgCertFile = secTG.Key("CERT_FILE").String()
+	TgKeyFile = secTG.Key("KEY_FILE").String()
+	TgSecret = secTG.Key("SECRET").String()
+	TgAPIURL = secTG.Key("API_URL").String()
+	TgAdmins, err = parseAdmins(secTG.Key("ADMINS").String())
+	if err != nil {
+		fmt.Println("Ошибка в ADMINS:", err.Error())
+		return
+	}
+	secSec := cfg.Section("security")
+	KeyFileName = secSec.Key("MASTER_KEY_FILE").String()
+	ConfirmTimeout = secSec.Key("CONFIRM_TIMEOUT").MustInt64(ConfirmTimeout)
+	MetricsListen = cfg.Section("metrics").Key("LISTEN").String()
+	secMon := cfg.Section("monitor")
+	RemindStart = secMon.Key("REMIND_START").MustInt64(RemindStart)
+	RemindMax = secMon.Key("REMIND_MAX").MustInt64(RemindMax)
+	StaleAfter = secMon.Key("STALE_AFTER").MustInt64(StaleAfter)
+	MissedWindow = secMon.Key("MISSED_WINDOW").MustInt(MissedWindow)
+	AutoOffMissed = secMon.Key("AUTOOFF_MISSED").MustInt(AutoOffMissed)
+	AutoOffCooldown = secMon.Key("AUTOOFF_COOLDOWN").MustInt(AutoOffCooldown)
+	err = checkAutoOffConf()
+	if err != nil {
+		fmt.Println("Ошибка в [monitor]:", err.Error())
+		return
+	}
+	TxPollInterval = secMon.Key("TX_POLL").MustInt(TxPollInterval)
+	TxPollTimeout = secMon.Key("TX_TIMEOUT").MustInt(TxPollTimeout)
+	RankLimit = secMon.Key("RANK_LIMIT").MustInt(RankLimit)
+	StakeMargin = float32(secMon.Key("STAKE_MARGIN").MustFloat64(float64(StakeMargin)))
+	StakeChangeAbs = float32(secMon.Key("STAKE_CHANGE_ABS").MustFloat64(float64(StakeChangeAbs)))
+	StakeChangePct = float32(secMon.Key("STAKE_CHANGE_PCT").MustFloat64(float64(StakeChangePct)))
+	WatchMax = secMon.Key("WATCH_MAX").MustInt(WatchMax)
+	if secMon.HasKey("MISSED_LEVELS") {
+		MissedLevels, err = parseMissedLevels(secMon.Key("MISSED_LEVELS").String())
+		if err != nil {
+			fmt.Println("Ошибка в MISSED_LEVELS:", err.Error())
+			return
+		}
+	}
 
 	// открываем соединение
-	session, err := mgo.Dial(DBAddress)
+	store, err := newUserStore(DBType, DBAddress)
 	if err != nil {
 		fmt.Println("Ошибка соединения с БД:", err.Error())
 		return
 	}
-	defer session.Close()
+	defer store.Close()
+
+	// приводим записи в БД к текущей схеме
+	err = runMigrations(store)
+	if err != nil {
+		fmt.Println("Ошибка миграции схемы БД:", err.Error())
+		return
+	}
+
+	// мастер-ключ для приватных ключей
+	err = loadMasterKey(KeyFileName)
+	if err != nil {
+		fmt.Println("Ошибка загрузки мастер-ключа:", err.Error())
+		return
+	}
+	if masterAEAD == nil {
+		fmt.Println("ВНИМАНИЕ! Не задан мастер-ключ, добавление приватных ключей невозможно")
+	}
+
+	if runCommand == "migrate_keys" {
+		loadAllUsers(store)
+		err = migrateKeys(store)
+		if err != nil {
+			fmt.Println("Ошибка шифрования приватных ключей:", err.Error())
+		}
+		return
+	} else if runCommand != "" {
+		fmt.Println("Неизвестная команда:", runCommand)
+		return
+	}
 
 	fmt.Println(time.Now())
 
 	// подключаемся к боту с помощью токена
-	bot, err := tgbotapi.NewBotAPI(TgTokenAPI)
+	bot, err := newBotAPI(TgTokenAPI)
 	if err != nil {
 		fmt.Println("Ошибка соединения с Telegram:", err.Error())
 		return
 	}
 
+	// в отладочном выводе tgbotapi есть текст сообщений, скрываем в нём ключи
+	tgbotapi.SetLogger(newRedactLogger())
 	bot.Debug = true
 	fmt.Printf("Авторизован: %s\n", bot.Self.UserName)
 
 	// Загружаем пользователей из базы
-	loadAllUsers(session)
+	loadAllUsers(store)
 
 	// в отдельном потоке запускаем функцию мониторинга
-	go monitor(bot)
+	go monitor(bot, store)
+
+	// метрики для Prometheus
+	startMetrics()
+
+	// канал в который будут прилетать новые сообщения
+	var updates tgbotapi.UpdatesChannel
+	if strings.ToLower(TgMode) == "webhook" {
+		updates, err = startWebhook(bot)
+	} else {
+		// при long polling вебхук должен быть снят
+		bot.RemoveWebhook()
 
-	// u - структура с конфигом для получения апдейтов
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+		// u - структура с конфигом для получения апдейтов
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
 
-	// используя конфиг u создаем канал в который будут прилетать новые сообщения
-	updates, err := bot.GetUpdatesChan(u)
+		// используя конфиг u создаем канал в который будут прилетать новые сообщения
+		updates, err = bot.GetUpdatesChan(u)
+	}
+	if err != nil {
+		fmt.Println("Ошибка получения сообщений Telegram:", err.Error())
+		return
+	}
 
 	// в канал updates прилетают структуры типа Update
 	// вычитываем их и обрабатываем
 	for update := range updates {
-		// универсальный ответ на любое сообщение
-		reply := ""
-		if update.Message == nil {
-			continue
-		}
-		/*if !update.Message.IsCommand() { // ignore any non-command Messages
-			continue
-		}*/
-
-		// логируем от кого какое сообщение пришло
-		fmt.Printf("[%s] %s\n", update.Message.From.UserName, update.Message.Text)
-
-		// свитч на обработку комманд
-		// комманда - сообщение, начинающееся с "/"
-		switch update.Message.Command() {
-
-		// выводим информацию о боте
-		case "start":
-			reply = HelpMsg
-		case "help":
-			reply = HelpMsg
-
-		// выводим информацию о мастерноде(валидаторе!)
-		case "node_info":
-			if update.Message.CommandArguments() == "" {
-				oUsr := getUser(update.Message.Chat.ID)
-				if oUsr.PubKey != "" {
-					cndI := getValidInfo(oUsr.PubKey)
-					chekIt := "нет"
-					if oUsr.Notification == true {
-						chekIt = "да"
-					}
-					reply = fmt.Sprintf("Ключ: %s\nАдрес: %s\nПрив.ключ: %s\nСтатус: %s\nКомиссия: %d%%\nСтэк: %f\nОповещение: %s",
-						getMinString(oUsr.PubKey),
-						getMinString(oUsr.UserAddress),
-						getMinString(oUsr.PrivKey),
-						getNodeStatusString(cndI.StatusInt),
-						cndI.Commission,
-						cndI.TotalStake,
-						chekIt)
-				} else {
-					if oUsr.ChatID == 0 {
-						reply = "Добавьте мастерноду для слежения, командой /node_add"
-					} else {
-						reply = "Добавьте мастерноду для слежения, командой /node_edit"
-					}
-				}
+		handleUpdate(bot, store, update)
+	}
+}
+
+// Обработка сообщения от Telegram (одинаково для long polling и webhook)
+func handleUpdate(bot *tgbotapi.BotAPI, store UserStore, update tgbotapi.Update) {
+	var err error
+	// универсальный ответ на любое сообщение
+	reply := ""
+	// кнопки под ответом (nil - без кнопок)
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if update.CallbackQuery != nil {
+		handleCallback(bot, store, update.CallbackQuery)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	/*if !update.Message.IsCommand() { // ignore any non-command Messages
+		return
+	}*/
+
+	// логируем от кого какое сообщение пришло
+	fmt.Printf("[%s] %s\n", update.Message.From.UserName, redactSecrets(update.Message.Text))
+
+	lang := chatLang(store, update.Message.Chat.ID, update.Message.From)
+
+	// сообщение с приватным ключом не оставляем в истории чата
+	if containsSecret(update.Message.Text) {
+		_, err = bot.DeleteMessage(tgbotapi.DeleteMessageConfig{ChatID: update.Message.Chat.ID, MessageID: update.Message.MessageID})
+		if err != nil {
+			fmt.Println("Ошибка удаления сообщения:", err)
+			sendMessage(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr(lang, "secret_not_deleted")))
+		}
+		if !update.Message.Chat.IsPrivate() {
+			sendMessage(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr(lang, "secret_in_group")))
+		}
+	}
+
+	// свитч на обработку комманд
+	// комманда - сообщение, начинающееся с "/"
+	switch update.Message.Command() {
+
+	// выводим информацию о боте
+	case "start":
+		reply = tr(lang, "help")
+	case "help":
+		reply = tr(lang, "help")
+
+	// выводим информацию о мастернодах(валидаторах!) пользователя
+	case "node_info":
+		oUsr := getUser(update.Message.Chat.ID)
+		argument := update.Message.CommandArguments()
+		if argument == "" {
+			reply, keyboard = nodeListMessage(lang, update.Message.From.ID, oUsr)
+		} else if iN := findNode(oUsr, argument); iN != -1 {
+			reply = getNodeInfoString(lang, oUsr.Nodes[iN])
+			keyboard = nodeKeyboard(lang, update.Message.From.ID, iN, oUsr.Nodes[iN])
+		} else {
+			reply, keyboard = searchMessage(lang, argument, 0)
+		}
+
+	// добавить мастерноду в список мониторинга
+	case "node_add":
+		oUsr := getUser(update.Message.Chat.ID)
+		if update.Message.CommandArguments() == "" {
+			reply = tr(lang, "node_add_format")
+		} else {
+			fmt.Println("node_add")
+			fmt.Println(redactSecrets(update.Message.CommandArguments()))
+
+			arguments := strings.Fields(update.Message.CommandArguments())
+			argLen := len(arguments)
+
+			fmt.Println(redactSecrets(fmt.Sprintf("%#v", arguments)))
+			fmt.Printf("ВСЕГО %d\n", argLen)
+
+			// аргументов 1 или 3, и ещё может быть метка
+			node1 := nodeData{Notification: true}
+			if argLen == 1 || argLen == 2 {
+				node1.PubKey = arguments[0]
+			} else if argLen == 3 || argLen == 4 {
+				node1.PubKey = arguments[0]
+				node1.UserAddress = arguments[1]
+				node1.PrivKey = arguments[2]
+			}
+			if argLen == 2 || argLen == 4 {
+				node1.Label = arguments[argLen-1]
+			}
+
+			errCheck := checkNodeKeys(node1.PubKey, node1.UserAddress, node1.PrivKey)
+			if errCheck == nil && node1.Label != "" {
+				errCheck = checkLabel(node1.Label)
+			}
+			encKey, errKey := encryptKey(node1.PrivKey)
+			node1.PrivKey = encKey
+
+			if node1.PubKey == "" {
+				reply = tr(lang, "node_add_format")
+			} else if errCheck != nil {
+				reply = tr(lang, "node_add_invalid", trErr(lang, errCheck))
+			} else if errKey != nil {
+				reply = tr(lang, "privkey_not_saved", errKey.Error())
+			} else if findNode(oUsr, node1.PubKey) != -1 {
+				reply = tr(lang, "node_add_exists")
+			} else if node1.Label != "" && findNode(oUsr, node1.Label) != -1 {
+				// в том числе метка, совпадающая с паблик-кеем другой мастерноды
+				reply = tr(lang, "label_busy", node1.Label)
 			} else {
-				// TODO: надо еще проверять формат pubkey!!! или вообще в списке мастернод-кандидатов, прежде чем в базу добавлять
-				resSrch := searchValid(update.Message.CommandArguments())
-				amntRes := len(resSrch)
-
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Найдено мастернод: %d", amntRes)))
-
-				for iN, oNd := range resSrch {
-					reply = fmt.Sprintf("= Мастернода %d ==========\nКлюч: %s\nСтатус: %s\nКомиссия: %d%%\nСтэк: %f",
-						(iN + 1),
-						oNd.PubKey,
-						getNodeStatusString(oNd.StatusInt),
-						oNd.Commission,
-						oNd.TotalStake)
-					msg := tgbotapi.NewMessage(update.Message.Chat.ID, reply)
-
-					btnKeyboard := tgbotapi.NewInlineKeyboardMarkup(
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonSwitch("Ключ", oNd.PubKey),
-						),
-					)
-
-					msg.ReplyMarkup = &btnKeyboard
-					bot.Send(msg)
-				}
-				continue
+				addNode(store, update.Message.Chat.ID, update.Message.From.UserName, lang, node1)
+				reply = tr(lang, "node_added")
+			}
+		}
+	// изменить pubkey у мастерноды
+	case "node_edit":
+		oUsr := getUser(update.Message.Chat.ID)
+		if len(oUsr.Nodes) > 0 {
+			fmt.Println("node_edit")
+			fmt.Println(redactSecrets(update.Message.CommandArguments()))
+			arguments := strings.Fields(update.Message.CommandArguments())
+			argLen := len(arguments)
+			fmt.Println(redactSecrets(fmt.Sprintf("%#v", arguments)))
+			fmt.Printf("ВСЕГО %d\n", argLen)
+
+			// аргументов 1 или 3, перед ними метка (если мастернод несколько)
+			idxNode := 0
+			if argLen == 2 || argLen == 4 {
+				idxNode = findNode(oUsr, arguments[0])
+				arguments = arguments[1:]
+				argLen--
+			} else if len(oUsr.Nodes) > 1 {
+				idxNode = -1
 			}
 
-		// добавить мастерноду в список мониторинга
-		case "node_add":
-			oUsr := getUser(update.Message.Chat.ID)
-			//if oUsr.PubKey == "" {
-			if oUsr.ChatID == 0 {
-				if update.Message.CommandArguments() == "" {
-					reply = "Неправильный формат команды. Должен быть /node_add [pubkey], где pubkey-публичный ключ добавляемой мастерноды\n" +
-						"или (!-только если доверяете нам) /node_add [pubkey] [usradr] [privkey], где usradr-адрес пользователя и privkey-приватный ключ"
+			if idxNode == -1 {
+				reply = tr(lang, "node_not_found", tr(lang, "usage_node_edit"))
+			} else if argLen == 1 {
+				// адрес владельца у мастерноды уже может быть задан
+				errCheck := checkNodeKeys(arguments[0], oUsr.Nodes[idxNode].UserAddress, "")
+				if errCheck != nil {
+					reply = tr(lang, "node_edit_invalid", trErr(lang, errCheck))
 				} else {
-					fmt.Println("node_add")
-					fmt.Println(update.Message.CommandArguments())
-
-					arguments := strings.Split(update.Message.CommandArguments(), " ")
-					argLen := len(arguments)
-
-					fmt.Printf("%#v\n", arguments)
-					fmt.Printf("ВСЕГО %d\n", argLen)
-
-					// аргументов или 1 или 3!
-					if argLen == 0 || argLen == 2 || argLen > 4 {
-						reply = "Неправильный формат команды. Должен быть /node_add [pubkey], где pubkey-публичный ключ добавляемой мастерноды\n" +
-							"или (!-только если доверяете нам) /node_add [pubkey] [usradr] [privkey], где usradr-адрес пользователя и privkey-приватный ключ"
-					} else if argLen == 1 {
-						// TODO: надо еще проверять формат pubkey!!! или вообще в списке мастернод-кандидатов, прежде чем в базу добавлять
-						usr1 := usrData{
-							PubKey:       arguments[0],
-							UserName:     update.Message.From.UserName,
-							ChatID:       update.Message.Chat.ID,
-							Notification: true,
-						}
-						addUser(session, usr1)
-						reply = "Мастернода успешно привязана к Вам."
-					} else if argLen == 3 {
-						// TODO: надо еще проверять формат pubkey и privkey!!! или вообще в списке мастернод-кандидатов, прежде чем в базу добавлять
-						usr1 := usrData{
-							PubKey:       arguments[0],
-							UserAddress:  arguments[1],
-							PrivKey:      arguments[2],
-							UserName:     update.Message.From.UserName,
-							ChatID:       update.Message.Chat.ID,
-							Notification: true,
-						}
-						addUser(session, usr1)
-						reply = "Мастернода успешно привязана к Вам."
-					}
+					editUserKey(store, update.Message.Chat.ID, idxNode, nodeData{PubKey: arguments[0]})
+					reply = tr(lang, "node_edit_pubkey")
 				}
-			} else {
-				reply = "Мастернода уже привязана к вам. Если хотите изменить, воспользуйтесь командой /node_edit"
-			}
-		// изменить pubkey у мастерноды
-		case "node_edit":
-			oUsr := getUser(update.Message.Chat.ID)
-			if oUsr.ChatID != 0 {
-				if update.Message.CommandArguments() == "" {
-					reply = "Неправильный формат команды. Должен быть /node_edit [pubkey], где pubkey-публичный ключ мастерноды\n" +
-						"или (!-только если доверяете нам) /node_edit [pubkey] [usradr] [privkey], где usradr-адрес пользователя и privkey-приватный ключ"
+			} else if argLen == 3 {
+				errCheck := checkNodeKeys(arguments[0], arguments[1], arguments[2])
+				encKey, errKey := encryptKey(arguments[2])
+				if errCheck != nil {
+					reply = tr(lang, "node_edit_invalid", trErr(lang, errCheck))
+				} else if errKey != nil {
+					reply = tr(lang, "privkey_not_saved", errKey.Error())
 				} else {
-					fmt.Println("node_edit")
-					fmt.Println(update.Message.CommandArguments())
-					arguments := strings.Split(update.Message.CommandArguments(), " ")
-					argLen := len(arguments)
-					fmt.Printf("%#v\n", arguments)
-					fmt.Printf("ВСЕГО %d\n", argLen)
-
-					// аргументов или 1 или 3!
-					if argLen == 0 || argLen == 2 || argLen > 4 {
-						reply = "Неправильный формат команды. Должен быть /node_edit [pubkey], где pubkey-публичный ключ мастерноды\n" +
-							"или (!-только если доверяете нам) /node_edit [pubkey] [usradr] [privkey], где usradr-адрес пользователя и privkey-приватный ключ"
-					} else if argLen == 1 {
-						// TODO: надо еще проверять формат pubkey!!! или вообще в списке мастернод-кандидатов, прежде чем в базу добавлять
-						usr1 := usrData{ChatID: update.Message.Chat.ID, PubKey: arguments[0]}
-						editUserKey(session, usr1)
-						reply = "Мастернода успешно изменена. Изменен [pubkey]."
-					} else if argLen == 3 {
-						usr1 := usrData{ChatID: update.Message.Chat.ID, PubKey: arguments[0], UserAddress: arguments[1], PrivKey: arguments[2]}
-						editUserKey(session, usr1)
-						reply = "Мастернода успешно изменена. Изменены [pubkey], [usradr] и [privkey] ."
-					}
+					editUserKey(store, update.Message.Chat.ID, idxNode, nodeData{PubKey: arguments[0], UserAddress: arguments[1], PrivKey: encKey})
+					reply = tr(lang, "node_edit_keys")
 				}
 			} else {
-				reply = "Мастернода ещё не привязана к вам. Воспользуйтесь командой /node_add"
+				reply = tr(lang, "node_edit_format")
 			}
-		// удаление мастерноды
-		case "node_del":
-			oUsr := getUser(update.Message.Chat.ID)
-			delNode(session, oUsr.ChatID)
-			reply = "Мастернода отвязана"
-		// изменить статус уведомления да/нет
-		case "notification":
-			oUsr := getUser(update.Message.Chat.ID)
-			reply = editNodeNotif(session, oUsr.ChatID)
-
-		//FIXME: вспомогательная команда - для теста
-		/*case "cleandb":
-		cleanDB(session)
-		reply = "База очищена"*/
-		// вкл/откл мастерноду
-		case "candidate":
-			oUsr := getUser(update.Message.Chat.ID)
-			if oUsr.PrivKey != "" {
-				argument := update.Message.CommandArguments()
-
-				if argument == "" {
-					reply = "Неправильный формат команды. Не уазано состояние в которое нужно перевести мастерноду:\n" +
-						"on или 1 - включить, off или 0 - выключить"
-				} else {
-					statusMnode := false
-					badCommand := false
-					if argument == "1" || argument == "On" || argument == "on" || argument == "ON" {
-						statusMnode = true
-					} else if argument == "0" || argument == "Off" || argument == "off" || argument == "OFF" {
-						statusMnode = false
-					} else {
-						badCommand = true
-					}
-					if badCommand != true {
-						// Посылаем транзакцию
-						tx, err := SetCandidateTransaction(oUsr.UserAddress, oUsr.PrivKey, oUsr.PubKey, statusMnode)
-						if err != nil {
-							reply = fmt.Sprintf("Произошла ошибка: %s", err.Error())
-						} else {
-							reply = fmt.Sprintf("Состояние мастерноды успешно изменено.\nТранзакция: %s", tx)
-						}
-					} else {
-						reply = "Неправильный формат команды. Не уазано состояние в которое нужно перевести мастерноду:\n" +
-							"on или 1 - включить, off или 0 - выключить"
-					}
-				}
+		} else {
+			reply = tr(lang, "no_nodes")
+		}
+	// удаление мастерноды
+	case "node_del":
+		oUsr := getUser(update.Message.Chat.ID)
+		argument := update.Message.CommandArguments()
+		idxNode := -1
+		if argument != "" {
+			idxNode = findNode(oUsr, argument)
+		} else if len(oUsr.Nodes) == 1 {
+			idxNode = 0
+		}
+		if idxNode != -1 {
+			delNode(store, oUsr.ChatID, idxNode)
+			reply = tr(lang, "node_deleted")
+		} else {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_node_del"))
+		}
+	// изменить статус уведомления да/нет
+	case "notification":
+		oUsr := getUser(update.Message.Chat.ID)
+		argument := update.Message.CommandArguments()
+		idxNode := -1
+		if argument != "" {
+			idxNode = findNode(oUsr, argument)
+		}
+		if len(oUsr.Nodes) == 0 {
+			reply = tr(lang, "no_nodes")
+		} else if argument != "" && idxNode == -1 {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_notification"))
+		} else {
+			reply = editNodeNotif(store, oUsr.ChatID, lang, idxNode)
+		}
+
+	// пороги оповещения о пропуске блоков
+	case "missed":
+		oUsr := getUser(update.Message.Chat.ID)
+		arguments := strings.Fields(update.Message.CommandArguments())
+		idxNode := -1
+		if len(arguments) > 0 {
+			idxNode = findNode(oUsr, arguments[0])
+		}
+		if idxNode != -1 {
+			arguments = arguments[1:]
+		} else if len(oUsr.Nodes) == 1 && len(arguments) <= 1 {
+			idxNode = 0
+		}
+
+		if len(oUsr.Nodes) == 0 {
+			reply = tr(lang, "no_nodes")
+		} else if idxNode == -1 {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_missed"))
+		} else if len(arguments) == 0 {
+			oNd := oUsr.Nodes[idxNode]
+			missed, wnd := getMissedBlocks(oNd.PubKey)
+			reply = tr(lang, "missed_info",
+				oNd.Label, missed, wnd, strings.Trim(fmt.Sprint(getMissedLevels(oNd)), "[]"))
+		} else {
+			levels, err := parseMissedLevels(arguments[0])
+			if err != nil {
+				reply = tr(lang, "missed_format", trErr(lang, err))
+			} else {
+				editNodeMissed(store, oUsr.ChatID, idxNode, levels)
+				reply = tr(lang, "missed_changed", strings.Trim(fmt.Sprint(levels), "[]"))
+			}
+		}
+
+	// место по стэку и пороги оповещения о месте и запасе стэка
+	case "rank":
+		oUsr := getUser(update.Message.Chat.ID)
+		arguments := strings.Fields(update.Message.CommandArguments())
+		idxNode := -1
+		if len(arguments) == 1 || len(arguments) == 3 {
+			idxNode = findNode(oUsr, arguments[0])
+			arguments = arguments[1:]
+		} else if len(oUsr.Nodes) == 1 {
+			idxNode = 0
+		}
+
+		if len(oUsr.Nodes) == 0 {
+			reply = tr(lang, "no_nodes")
+		} else if idxNode == -1 {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_rank"))
+		} else if len(arguments) == 0 {
+			reply = getRankString(lang, oUsr.Nodes[idxNode])
+		} else if len(arguments) != 2 {
+			reply = tr(lang, "rank_format", tr(lang, "usage_rank"))
+		} else {
+			rankLimit, stakeMargin, err := parseRankLimits(arguments[0], arguments[1])
+			if err != nil {
+				reply = tr(lang, "rank_format", trErr(lang, err))
 			} else {
-				if oUsr.ChatID != 0 {
-					reply = "Не указан приватный ключ. Воспользуйтесь командой /node_edit"
+				editNodeRank(store, oUsr.ChatID, idxNode, rankLimit, stakeMargin)
+				oUsr = getUser(update.Message.Chat.ID)
+				reply = tr(lang, "rank_changed") + "\n" + getRankString(lang, oUsr.Nodes[idxNode])
+			}
+		}
+
+	// вкл/откл автоотключения мастерноды при пропуске блоков
+	case "autooff":
+		oUsr := getUser(update.Message.Chat.ID)
+		arguments := strings.Fields(update.Message.CommandArguments())
+		argument := ""
+		idxNode := -1
+		// с одной мастернодой метку можно не указывать, без состояния - подсказка формата
+		if len(arguments) <= 1 && len(oUsr.Nodes) == 1 {
+			idxNode = 0
+			if len(arguments) == 1 {
+				argument = arguments[0]
+			}
+		} else if len(arguments) == 2 {
+			idxNode = findNode(oUsr, arguments[0])
+			argument = arguments[1]
+		}
+		statusAuto, okCommand := parseOnOff(argument)
+
+		if len(oUsr.Nodes) == 0 {
+			reply = tr(lang, "no_nodes")
+		} else if idxNode == -1 {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_autooff"))
+		} else if oUsr.Nodes[idxNode].PrivKey == "" {
+			reply = tr(lang, "no_privkey_edit")
+		} else if okCommand != true {
+			reply = tr(lang, "autooff_format")
+		} else {
+			editNodeAutoOff(store, oUsr.ChatID, idxNode, statusAuto)
+			if statusAuto {
+				reply = tr(lang, "autooff_on", oUsr.Nodes[idxNode].Label, AutoOffMissed, MissedWindow)
+			} else {
+				reply = tr(lang, "autooff_off", oUsr.Nodes[idxNode].Label)
+			}
+		}
+
+	// язык сообщений бота
+	case "lang":
+		argument := strings.ToLower(update.Message.CommandArguments())
+		if argument == "" {
+			reply = tr(lang, "lang_current", lang, strings.Join(langList(), ", "))
+		} else if _, ok := catalogs[argument]; !ok {
+			reply = tr(lang, "lang_unknown", argument, strings.Join(langList(), ", "))
+		} else {
+			editUserLang(store, update.Message.Chat.ID, update.Message.From.UserName, argument)
+			reply = tr(argument, "lang_changed", argument)
+		}
+
+	//FIXME: вспомогательная команда - для теста
+	/*case "cleandb":
+	cleanDB(store)
+	reply = "База очищена"*/
+	// вкл/откл мастерноду
+	case "candidate":
+		oUsr := getUser(update.Message.Chat.ID)
+		arguments := strings.Fields(update.Message.CommandArguments())
+		argument := ""
+		idxNode := -1
+		// с одной мастернодой метку можно не указывать, без состояния - подсказка формата
+		if len(arguments) <= 1 && len(oUsr.Nodes) == 1 {
+			idxNode = 0
+			if len(arguments) == 1 {
+				argument = arguments[0]
+			}
+		} else if len(arguments) == 2 {
+			idxNode = findNode(oUsr, arguments[0])
+			argument = arguments[1]
+		}
+
+		if len(oUsr.Nodes) > 1 && idxNode == -1 {
+			reply = tr(lang, "node_not_found", tr(lang, "usage_candidate"))
+		} else if idxNode != -1 && oUsr.Nodes[idxNode].PrivKey != "" {
+			oNd := oUsr.Nodes[idxNode]
+			if argument == "" {
+				reply = tr(lang, "candidate_format")
+			} else {
+				statusMnode, okCommand := parseOnOff(argument)
+				if okCommand == true {
+					// транзакция отправится только после подтверждения
+					reply, keyboard = askCandidate(lang, update.Message.Chat.ID, update.Message.From.ID, oNd, statusMnode)
 				} else {
-					reply = "Не указан приватный ключ. Воспользуйтесь командой /node_add"
+					reply = tr(lang, "candidate_format")
 				}
 			}
+		} else {
+			if len(oUsr.Nodes) != 0 {
+				reply = tr(lang, "no_privkey_edit")
+			} else {
+				reply = tr(lang, "no_privkey_add")
+			}
 		}
 
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID, reply)
-		_, err = bot.Send(msg)
-		if err != nil {
-			fmt.Println("Ошибка отправки сообщения:", err)
+	// подтверждение транзакции кодом
+	case "confirm":
+		reply = runConfirm(bot, lang, update.Message.Chat.ID, update.Message.From.ID, update.Message.CommandArguments())
+	// отмена транзакции, ожидающей подтверждения
+	case "cancel":
+		reply = cancelConfirm(lang, update.Message.Chat.ID, update.Message.From.ID, "")
+
+	// валидаторы по стэку
+	case "top":
+		amnt := topDefault
+		if argument := update.Message.CommandArguments(); argument != "" {
+			amnt, err = strconv.Atoi(argument)
+			if err != nil || amnt <= 0 {
+				reply = tr(lang, "top_format")
+				break
+			}
+		}
+		reply, keyboard = topMessage(lang, amnt, 0)
+	// сводка по сети
+	case "network":
+		reply = networkMessage(lang)
+
+	// подписка на чужую мастерноду: только оповещения
+	case "watch":
+		oUsr := getUser(update.Message.Chat.ID)
+		arguments := strings.Fields(update.Message.CommandArguments())
+		if len(arguments) == 0 {
+			reply = watchListMessage(lang, oUsr)
+			break
+		}
+		if len(arguments) > 2 {
+			reply = tr(lang, "watch_format", strings.Join(watchAlerts, ","))
+			break
 		}
+		alertStr := ""
+		if len(arguments) == 2 {
+			alertStr = arguments[1]
+		}
+		alerts, errAlerts := parseWatchAlerts(alertStr)
+		if err = checkPubKey(arguments[0]); err != nil {
+			reply = tr(lang, "watch_invalid", trErr(lang, err))
+		} else if errAlerts != nil {
+			reply = tr(lang, "watch_invalid", trErr(lang, errAlerts))
+		} else if findNode(oUsr, arguments[0]) != -1 {
+			reply = tr(lang, "watch_own")
+		} else if findWatch(oUsr, arguments[0]) == -1 && len(oUsr.Watch) >= WatchMax {
+			reply = tr(lang, "watch_max", WatchMax)
+		} else {
+			editUserWatch(store, update.Message.Chat.ID, update.Message.From.UserName, lang, watchData{PubKey: arguments[0], Alerts: alerts})
+			reply = tr(lang, "watch_added", getMinString(arguments[0]), strings.Join(alerts, ","))
+			if _, ok := allCand.Get(arguments[0]); !ok {
+				reply += "\n" + tr(lang, "watch_not_candidate")
+			}
+		}
+	// отписка от мастерноды
+	case "unwatch":
+		oUsr := getUser(update.Message.Chat.ID)
+		argument := update.Message.CommandArguments()
+		idxWatch := findWatch(oUsr, argument)
+		if len(oUsr.Watch) == 0 {
+			reply = tr(lang, "watch_empty")
+		} else if argument == "all" {
+			delUserWatch(store, oUsr.ChatID, -1)
+			reply = tr(lang, "unwatch_all")
+		} else if idxWatch == -1 {
+			reply = tr(lang, "unwatch_format")
+		} else {
+			delUserWatch(store, oUsr.ChatID, idxWatch)
+			reply = tr(lang, "unwatch_done", getMinString(argument))
+		}
+
+	// делегирование в мастерноду и отзыв монет
+	case "delegate", "unbond":
+		reply, keyboard = askStakeTx(lang, update.Message.Chat.ID, update.Message.From.ID, update.Message.Command(), strings.Fields(update.Message.CommandArguments()))
+	// изменение адреса награды и владельца мастерноды
+	case "edit_candidate", "commission":
+		reply, keyboard = askEditCandidate(store, lang, update.Message.Chat.ID, update.Message.From.ID, strings.Fields(update.Message.CommandArguments()))
+	}
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, reply)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
 	}
+	sendMessage(bot, msg)
 }
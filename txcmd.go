@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Комиссии транзакций в монетах сети при GasPrice = 1
+const (
+	delegateFee      = 0.2
+	unbondFee        = 0.2
+	editCandidateFee = 10.0
+)
+
+var coinRegexp = regexp.MustCompile(`^[A-Z0-9]{3,10}$`)
+
+// SDK для подписи транзакций от адреса пользователя. Ключ хранится
+// зашифрованным, расшифровываем только на время подписи
+func newSignSDK(usrAddr string, keyString string) (m.SDK, error) {
+	keyString, err := decryptKey(keyString)
+	if err != nil {
+		return m.SDK{}, err
+	}
+	return m.SDK{
+		MnAddress:     getMnAddress(),
+		AccAddress:    usrAddr,
+		AccPrivateKey: keyString,
+	}, nil
+}
+
+// Функция транзакции делегирования в мастерноду
+func DelegateTransaction(usrAddr string, keyString string, pubKeyMN string, coin string, amount float32) (string, error) {
+	sdk, err := newSignSDK(usrAddr, keyString)
+	if err != nil {
+		return "", err
+	}
+	sndDt := m.TxDelegateData{
+		PubKey:   pubKeyMN,
+		Coin:     coin,
+		Value:    amount,
+		GasCoin:  CoinMinter,
+		GasPrice: 1,
+	}
+	return sdk.TxDelegate(&sndDt)
+}
+
+// Функция транзакции отзыва делегированных монет из мастерноды
+func UnbondTransaction(usrAddr string, keyString string, pubKeyMN string, coin string, amount float32) (string, error) {
+	sdk, err := newSignSDK(usrAddr, keyString)
+	if err != nil {
+		return "", err
+	}
+	sndDt := m.TxUnbondData{
+		PubKey:   pubKeyMN,
+		Coin:     coin,
+		Value:    amount,
+		GasCoin:  CoinMinter,
+		GasPrice: 1,
+	}
+	return sdk.TxUnbond(&sndDt)
+}
+
+// Функция транзакции изменения адреса награды и владельца мастерноды
+func EditCandidateTransaction(usrAddr string, keyString string, pubKeyMN string, rewardAddr string, ownerAddr string) (string, error) {
+	sdk, err := newSignSDK(usrAddr, keyString)
+	if err != nil {
+		return "", err
+	}
+	sndDt := m.TxEditCandidateData{
+		PubKey:        pubKeyMN,
+		RewardAddress: rewardAddr,
+		OwnerAddress:  ownerAddr,
+		GasCoin:       CoinMinter,
+		GasPrice:      1,
+	}
+	return sdk.TxEditCandidate(&sndDt)
+}
+
+// Проверка баланса перед транзакцией: хватает ли amount монеты coin и комиссии в CoinMinter
+func checkTxBalance(usrAddr string, coin string, amount float32, fee float32) error {
+	sdk := m.SDK{
+		MnAddress: getMnAddress(),
+	}
+	var balance map[string]float32
+	err := mnCall(sdk.MnAddress, func() error {
+		var err error
+		balance, err = sdk.GetBalance(usrAddr)
+		return err
+	})
+	if err != nil {
+		nodeError(err)
+		return newUserError("err_balance", err.Error())
+	}
+	if coin == CoinMinter {
+		amount += fee
+	} else if balance[CoinMinter] < fee {
+		return newUserError("err_no_funds", CoinMinter, fee, balance[CoinMinter])
+	}
+	if balance[coin] < amount {
+		return newUserError("err_no_funds", coin, amount, balance[coin])
+	}
+	return nil
+}
+
+// Проверка перед отзывом: у адреса должно быть делегировано в мастерноду не меньше amount монеты coin
+func checkUnbondStake(usrAddr string, pubKey string, coin string, amount float32) error {
+	sdk := m.SDK{
+		MnAddress: getMnAddress(),
+	}
+	var cnd m.CandidateInfo
+	err := mnCall(sdk.MnAddress, func() error {
+		var err error
+		cnd, err = sdk.GetCandidate(pubKey)
+		return err
+	})
+	if err != nil {
+		nodeError(err)
+		return newUserError("err_stake", err.Error())
+	}
+	return checkAddressStake(newStakesInfo(cnd), usrAddr, coin, amount)
+}
+
+// Сколько монеты coin делегировано с адреса, ошибка - если меньше amount
+func checkAddressStake(stakes []stakes_info, usrAddr string, coin string, amount float32) error {
+	var stake float32
+	for _, oneStake := range stakes {
+		if strings.EqualFold(oneStake.Owner, usrAddr) && oneStake.Coin == coin {
+			stake += oneStake.Value32
+		}
+	}
+	if stake < amount {
+		return newUserError("err_no_stake", coin, amount, stake)
+	}
+	return nil
+}
+
+// Разбор суммы и монеты: "10.5 bip" -> 10.5, "BIP"
+func parseAmountCoin(amountStr string, coinStr string) (float32, string, error) {
+	amount, err := strconv.ParseFloat(amountStr, 32)
+	if err != nil || amount <= 0 {
+		return 0, "", newUserError("err_amount", amountStr)
+	}
+	coin := strings.ToUpper(coinStr)
+	if !coinRegexp.MatchString(coin) {
+		return 0, "", newUserError("err_coin", coinStr)
+	}
+	return float32(amount), coin, nil
+}
+
+// Выбор мастерноды пользователя для команды: метка первым аргументом или единственная мастернода,
+// возвращает индекс (-1 - не найдена) и остальные аргументы
+func pickNode(usr usrData, arguments []string, argLen int) (int, []string) {
+	if len(arguments) == argLen+1 {
+		return findNode(usr, arguments[0]), arguments[1:]
+	}
+	if len(arguments) == argLen && len(usr.Nodes) == 1 {
+		return 0, arguments
+	}
+	return -1, arguments
+}
+
+// Команды /delegate и /unbond: проверка аргументов и баланса, запрос подтверждения
+func askStakeTx(lang string, chatID int64, userID int, cmd string, arguments []string) (string, *tgbotapi.InlineKeyboardMarkup) {
+	oUsr := getUser(chatID)
+	if len(oUsr.Nodes) == 0 {
+		return tr(lang, "no_nodes"), nil
+	}
+	idxNode, arguments := pickNode(oUsr, arguments, 2)
+	if len(arguments) != 2 {
+		return tr(lang, "stake_format", tr(lang, "usage_"+cmd)), nil
+	}
+	if idxNode == -1 {
+		return tr(lang, "node_not_found", tr(lang, "usage_"+cmd)), nil
+	}
+	oNd := oUsr.Nodes[idxNode]
+	if oNd.PrivKey == "" {
+		return tr(lang, "no_privkey_edit"), nil
+	}
+	amount, coin, err := parseAmountCoin(arguments[0], arguments[1])
+	if err != nil {
+		return tr(lang, "stake_format", trErr(lang, err)), nil
+	}
+
+	fee := float32(delegateFee)
+	if cmd == "unbond" {
+		fee = unbondFee
+		// для отзыва монеты должны быть в мастерноде, а на адресе - только комиссия
+		err = checkTxBalance(oNd.UserAddress, CoinMinter, 0, fee)
+		if err == nil {
+			err = checkUnbondStake(oNd.UserAddress, oNd.PubKey, coin, amount)
+		}
+	} else {
+		err = checkTxBalance(oNd.UserAddress, coin, amount, fee)
+	}
+	if err != nil {
+		return tr(lang, "tx_check_failed", trErr(lang, err)), nil
+	}
+
+	text := tr(lang, cmd+"_confirm",
+		oNd.Label,
+		getMinString(oNd.PubKey),
+		amount, coin,
+		CoinMinter,
+		fmt.Sprintf("%g %s", fee, CoinMinter))
+	pubKey := oNd.PubKey
+	return askConfirm(lang, chatID, userID, text, func(bot *tgbotapi.BotAPI, lang string) (string, error) {
+		oNd, ok := lookupSignerNode(chatID, pubKey)
+		if !ok {
+			return tr(lang, "callback_stale"), nil
+		}
+		var tx string
+		var err error
+		if cmd == "unbond" {
+			tx, err = UnbondTransaction(oNd.UserAddress, oNd.PrivKey, oNd.PubKey, coin, amount)
+		} else {
+			tx, err = DelegateTransaction(oNd.UserAddress, oNd.PrivKey, oNd.PubKey, coin, amount)
+		}
+		if err != nil {
+			return "", err
+		}
+		go trackTx(bot, chatID, lang, tx, nil)
+		return tr(lang, "tx_sent", tx), nil
+	})
+}
+
+// Удаление привязанных адреса и PrivKey мастерноды после смены владельца в БД и в памяти:
+// подписывать старым ключом больше нечего
+func editNodeKeysClear(store UserStore, chatID int64, pubKey string) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode := findNode(*usr, pubKey); idxNode != -1 {
+			usr.Nodes[idxNode].UserAddress = ""
+			usr.Nodes[idxNode].PrivKey = ""
+			usr.Nodes[idxNode].AutoOff = false
+		}
+	})
+}
+
+// Команда /edit_candidate: новый адрес награды и (не обязательно) владельца, запрос подтверждения
+func askEditCandidate(store UserStore, lang string, chatID int64, userID int, arguments []string) (string, *tgbotapi.InlineKeyboardMarkup) {
+	oUsr := getUser(chatID)
+	if len(oUsr.Nodes) == 0 {
+		return tr(lang, "no_nodes"), nil
+	}
+	// первым аргументом может быть метка, адрес владельца можно не указывать - останется прежним
+	idxNode := -1
+	args := arguments
+	if len(args) > 0 && !strings.HasPrefix(args[0], "Mx") {
+		idxNode = findNode(oUsr, args[0])
+		args = args[1:]
+	} else if len(oUsr.Nodes) == 1 {
+		idxNode = 0
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return tr(lang, "edit_candidate_format"), nil
+	}
+	if idxNode == -1 {
+		return tr(lang, "node_not_found", tr(lang, "usage_edit_candidate")), nil
+	}
+	oNd := oUsr.Nodes[idxNode]
+	if oNd.PrivKey == "" {
+		return tr(lang, "no_privkey_edit"), nil
+	}
+	rewardAddr := args[0]
+	ownerAddr := oNd.UserAddress
+	if len(args) == 2 {
+		ownerAddr = args[1]
+	}
+	for _, addr := range []string{rewardAddr, ownerAddr} {
+		if err := checkAddress(addr); err != nil {
+			return tr(lang, "edit_candidate_invalid", trErr(lang, err)), nil
+		}
+	}
+	if err := checkTxBalance(oNd.UserAddress, CoinMinter, 0, editCandidateFee); err != nil {
+		return tr(lang, "tx_check_failed", trErr(lang, err)), nil
+	}
+
+	text := tr(lang, "edit_candidate_confirm",
+		oNd.Label,
+		getMinString(oNd.PubKey),
+		rewardAddr,
+		ownerAddr,
+		CoinMinter,
+		fmt.Sprintf("%g %s", editCandidateFee, CoinMinter))
+	ownerChanged := !strings.EqualFold(ownerAddr, oNd.UserAddress)
+	if ownerChanged {
+		text += "\n" + tr(lang, "edit_candidate_owner_warn")
+	}
+	pubKey := oNd.PubKey
+	return askConfirm(lang, chatID, userID, text, func(bot *tgbotapi.BotAPI, lang string) (string, error) {
+		oNd, ok := lookupSignerNode(chatID, pubKey)
+		if !ok {
+			return tr(lang, "callback_stale"), nil
+		}
+		tx, err := EditCandidateTransaction(oNd.UserAddress, oNd.PrivKey, oNd.PubKey, rewardAddr, ownerAddr)
+		if err != nil {
+			return "", err
+		}
+		var check func() string
+		if ownerChanged {
+			// ключи убираем только когда смена владельца попала в блок
+			check = func() string {
+				editNodeKeysClear(store, chatID, pubKey)
+				return tr(lang, "edit_candidate_owner_changed", oNd.Label)
+			}
+		}
+		go trackTx(bot, chatID, lang, tx, check)
+		return tr(lang, "tx_sent", tx), nil
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAddressStake(t *testing.T) {
+	usrAddr := "Mx" + strings.Repeat("0a", 20)
+	otherAddr := "Mx" + strings.Repeat("0b", 20)
+	stakes := []stakes_info{
+		{Owner: usrAddr, Coin: "BIP", Value: "100", Value32: 100},
+		// адрес в другом регистре - тот же адрес
+		{Owner: "Mx" + strings.ToUpper(usrAddr[2:]), Coin: "BIP", Value: "50", Value32: 50},
+		{Owner: usrAddr, Coin: "ABC", Value: "20", Value32: 20},
+		{Owner: otherAddr, Coin: "BIP", Value: "500", Value32: 500},
+	}
+	tests := []struct {
+		name    string
+		coin    string
+		amount  float32
+		wantErr string
+	}{
+		{"all own stake", "BIP", 150, ""},
+		{"part of own stake", "BIP", 10, ""},
+		{"over own stake", "BIP", 150.5, "err_no_stake"},
+		{"other coin", "ABC", 20, ""},
+		{"over other coin", "ABC", 21, "err_no_stake"},
+		{"no stake in coin", "XYZ", 1, "err_no_stake"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userErrorKey(checkAddressStake(stakes, usrAddr, tt.coin, tt.amount)); got != tt.wantErr {
+				t.Fatalf("checkAddressStake(%s %g) = %q, want %q", tt.coin, tt.amount, got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Отслеживание отправленных транзакций (секция [monitor] INI файла)
+var (
+	TxPollInterval = 5   // Опрос мастерноды о транзакции раз в, сек.
+	TxPollTimeout  = 120 // Сколько сек. ждать включения транзакции в блок
+)
+
+// Ожидание транзакции в блоке и проверка результата, пользователю уходит сообщение.
+// check (если не nil) вызывается после успешной транзакции и возвращает доп. текст
+func trackTx(bot *tgbotapi.BotAPI, chatID int64, lang string, tx string, check func() string) {
+	deadline := time.Now().Add(time.Duration(TxPollTimeout) * time.Second)
+	for {
+		time.Sleep(time.Duration(TxPollInterval) * time.Second)
+
+		sdk := m.SDK{
+			MnAddress: getMnAddress(),
+		}
+		var res m.TransResponse
+		err := mnCall(sdk.MnAddress, func() error {
+			var err error
+			res, err = sdk.GetTransaction(tx)
+			return err
+		})
+		// пока транзакция не в блоке, мастернода отвечает ошибкой
+		if err != nil || res.Height == 0 {
+			if time.Now().After(deadline) {
+				fmt.Println("TX", tx, "не найдена:", err)
+				sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "tx_timeout", getMinString(tx), TxPollTimeout)))
+				return
+			}
+			continue
+		}
+
+		fmt.Println("TX", tx, "блок", res.Height, "код", res.Code)
+		if res.Code != 0 {
+			sendMessage(bot, tgbotapi.NewMessage(chatID, tr(lang, "tx_failed", getMinString(tx), res.Height, res.Code, res.Log)))
+			return
+		}
+		retTxt := tr(lang, "tx_included", getMinString(tx), res.Height)
+		if check != nil {
+			retTxt += "\n" + check()
+		}
+		sendMessage(bot, tgbotapi.NewMessage(chatID, retTxt))
+		return
+	}
+}
+
+// Проверка, что статус кандидата изменился после транзакции вкл/откл: текст и true, если изменился
+func checkCandidateStatus(lang string, pubKey string, status bool) (string, bool) {
+	sdk := m.SDK{
+		MnAddress: getMnAddress(),
+	}
+	var cnd m.CandidateInfo
+	err := mnCall(sdk.MnAddress, func() error {
+		var err error
+		cnd, err = sdk.GetCandidate(pubKey)
+		return err
+	})
+	if err != nil {
+		nodeError(err)
+		return tr(lang, "tx_check_error", err.Error()), false
+	}
+	return candidateStatusText(lang, cnd.StatusInt, status)
+}
+
+// Текст проверки по только что полученному статусу кандидата. Список валидаторов
+// здесь не смотрим: он из прошлого опроса, и выключенная нода в нём ещё валидатор
+func candidateStatusText(lang string, statusInt int, status bool) (string, bool) {
+	// числовое значение статуса: 1 - Offline, 2 - Online
+	if (statusInt == 2) == status {
+		return tr(lang, "tx_check_ok", getCandStatusString(lang, statusInt)), true
+	}
+	return tr(lang, "tx_check_mismatch", getCandStatusString(lang, statusInt)), false
+}
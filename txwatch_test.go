@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Каталоги сообщений для проверки текстов
+func loadTestCatalogs(t *testing.T) {
+	if err := loadCatalogs(LangDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCandidateStatusText(t *testing.T) {
+	loadTestCatalogs(t)
+	resetRegistries(t)
+	// в прошлом опросе нода была валидатором
+	valid := []candidate_info{{PubKey: "Mp1", TotalStake: 1000, StatusInt: 2}}
+	allCand.Set(valid, valid, false, time.Now())
+
+	tests := []struct {
+		name      string
+		statusInt int
+		status    bool
+		wantKey   string
+		wantState string
+		wantOk    bool
+	}{
+		{"switched off while validator", 1, false, "tx_check_ok", "status_offline", true},
+		{"still online after off", 2, false, "tx_check_mismatch", "status_online", false},
+		{"switched on", 2, true, "tx_check_ok", "status_online", true},
+		{"still offline after on", 1, true, "tx_check_mismatch", "status_offline", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txt, ok := candidateStatusText("en", tt.statusInt, tt.status)
+			want := tr("en", tt.wantKey, tr("en", tt.wantState))
+			if txt != want || ok != tt.wantOk {
+				t.Fatalf("candidateStatusText() = %q, %v, want %q, %v", txt, ok, want, tt.wantOk)
+			}
+		})
+	}
+}
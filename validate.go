@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	m "github.com/ValidatorCenter/minter-go-sdk"
+)
+
+var (
+	pubKeyRegexp  = regexp.MustCompile(`^Mp[0-9a-fA-F]{64}$`)
+	addressRegexp = regexp.MustCompile(`^Mx[0-9a-fA-F]{40}$`)
+	privKeyRegexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+// Проверка формата паблик-кея мастерноды
+func checkPubKey(pubKey string) error {
+	if !strings.HasPrefix(pubKey, "Mp") {
+		return newUserError("err_pubkey_prefix", getMinString(pubKey))
+	}
+	if !pubKeyRegexp.MatchString(pubKey) {
+		return newUserError("err_pubkey_format", getMinString(pubKey))
+	}
+	return nil
+}
+
+// Проверка формата адреса
+func checkAddress(usrAddr string) error {
+	if !strings.HasPrefix(usrAddr, "Mx") {
+		return newUserError("err_addr_prefix", getMinString(usrAddr))
+	}
+	if !addressRegexp.MatchString(usrAddr) {
+		return newUserError("err_addr_format", getMinString(usrAddr))
+	}
+	return nil
+}
+
+// Проверка метки мастерноды: findNode ищет и по метке, и по паблик-кею, поэтому метка
+// не должна быть похожа на ключ или адрес (например, адрес, оставшийся от формы с ключами)
+func checkLabel(label string) error {
+	lowLabel := strings.ToLower(label)
+	if strings.HasPrefix(lowLabel, "mp") || strings.HasPrefix(lowLabel, "mx") || privKeyRegexp.MatchString(label) {
+		return newUserError("err_label_format", getMinString(label))
+	}
+	return nil
+}
+
+// Проверка мастерноды перед сохранением: формат ключей, наличие среди кандидатов,
+// соответствие приватного ключа адресу и адреса владельцу мастерноды
+func checkNodeKeys(pubKey string, usrAddr string, privKey string) error {
+	if err := checkPubKey(pubKey); err != nil {
+		return err
+	}
+	if usrAddr != "" {
+		if err := checkAddress(usrAddr); err != nil {
+			return err
+		}
+	}
+	if privKey != "" {
+		if !privKeyRegexp.MatchString(privKey) {
+			return newUserError("err_privkey_format")
+		}
+		keyAddr, err := m.GetAddressPrivateKey(privKey)
+		if err != nil {
+			return newUserError("err_privkey_addr", err.Error())
+		}
+		if !strings.EqualFold(keyAddr, usrAddr) {
+			return newUserError("err_privkey_owner", getMinString(keyAddr), getMinString(usrAddr))
+		}
+	}
+
+	if len(allCand.All()) == 0 {
+		return newUserError("err_cand_not_loaded")
+	}
+	cnd := getValidInfo(pubKey)
+	if cnd.PubKey == "" {
+		return newUserError("err_cand_not_found", getMinString(pubKey))
+	}
+	if usrAddr != "" && !strings.EqualFold(cnd.CandidateAddress, usrAddr) {
+		return newUserError("err_not_owner", getMinString(usrAddr), getMinString(cnd.CandidateAddress))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Ключ ошибки для пользователя ("" - ошибки нет)
+func userErrorKey(err error) string {
+	if err == nil {
+		return ""
+	}
+	if uErr, ok := err.(*userError); ok {
+		return uErr.Key
+	}
+	return err.Error()
+}
+
+func TestCheckPubKey(t *testing.T) {
+	hex64 := strings.Repeat("0a", 32)
+	tests := []struct {
+		pubKey  string
+		wantErr string
+	}{
+		{"Mp" + hex64, ""},
+		{"Mp" + strings.ToUpper(hex64), ""},
+		{"", "err_pubkey_prefix"},
+		{"Mx" + hex64, "err_pubkey_prefix"},
+		{"mp" + hex64, "err_pubkey_prefix"},
+		{"Mp" + hex64[:62], "err_pubkey_format"},
+		{"Mp" + hex64 + "00", "err_pubkey_format"},
+		{"Mp" + hex64[:62] + "zz", "err_pubkey_format"},
+		{"Mp" + hex64 + " ", "err_pubkey_format"},
+	}
+	for _, tt := range tests {
+		if got := userErrorKey(checkPubKey(tt.pubKey)); got != tt.wantErr {
+			t.Errorf("checkPubKey(%q) = %q, want %q", tt.pubKey, got, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckLabel(t *testing.T) {
+	tests := []struct {
+		label   string
+		wantErr string
+	}{
+		{"", ""},
+		{"main", ""},
+		{"node2", ""},
+		{"Mx" + strings.Repeat("0a", 20), "err_label_format"},
+		{"Mp" + strings.Repeat("0a", 32), "err_label_format"},
+		{"mx01", "err_label_format"},
+		{strings.Repeat("0a", 32), "err_label_format"},
+	}
+	for _, tt := range tests {
+		if got := userErrorKey(checkLabel(tt.label)); got != tt.wantErr {
+			t.Errorf("checkLabel(%q) = %q, want %q", tt.label, got, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	hex40 := strings.Repeat("0a", 20)
+	tests := []struct {
+		usrAddr string
+		wantErr string
+	}{
+		{"Mx" + hex40, ""},
+		{"Mx" + strings.ToUpper(hex40), ""},
+		{"", "err_addr_prefix"},
+		{"Mp" + hex40, "err_addr_prefix"},
+		{"0x" + hex40, "err_addr_prefix"},
+		{"Mx" + hex40[:38], "err_addr_format"},
+		{"Mx" + hex40 + "00", "err_addr_format"},
+		{"Mx" + hex40[:38] + "g0", "err_addr_format"},
+	}
+	for _, tt := range tests {
+		if got := userErrorKey(checkAddress(tt.usrAddr)); got != tt.wantErr {
+			t.Errorf("checkAddress(%q) = %q, want %q", tt.usrAddr, got, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Сколько мастернод можно отслеживать подпиской (секция [monitor] INI файла)
+var WatchMax = 50
+
+// Типы оповещений подписки: выпадение из валидаторов, пропуск блоков, изменение стэка и комиссии
+var watchAlerts = []string{"down", "missed", "stake", "commission"}
+
+// Подписка пользователя на мастерноду по паблик-кею, -1 если не найдена
+func findWatch(usr usrData, pubKey string) int {
+	for iW, oneWatch := range usr.Watch {
+		if oneWatch.PubKey == pubKey {
+			return iW
+		}
+	}
+	return -1
+}
+
+// Есть ли у подписки тип оповещения
+func hasWatchAlert(oneWatch watchData, alert string) bool {
+	for _, oneAlert := range oneWatch.Alerts {
+		if oneAlert == alert {
+			return true
+		}
+	}
+	return false
+}
+
+// Разбор типов оповещений "down,stake" ("" - все типы)
+func parseWatchAlerts(str string) ([]string, error) {
+	if str == "" {
+		return append([]string{}, watchAlerts...), nil
+	}
+	alerts := []string{}
+	for _, oneAlert := range strings.Split(strings.ToLower(str), ",") {
+		oneAlert = strings.TrimSpace(oneAlert)
+		if oneAlert == "" {
+			continue
+		}
+		if !hasWatchAlert(watchData{Alerts: watchAlerts}, oneAlert) {
+			return nil, newUserError("err_watch_alert", oneAlert, strings.Join(watchAlerts, ","))
+		}
+		if !hasWatchAlert(watchData{Alerts: alerts}, oneAlert) {
+			alerts = append(alerts, oneAlert)
+		}
+	}
+	if len(alerts) == 0 {
+		return nil, newUserError("err_watch_alert", str, strings.Join(watchAlerts, ","))
+	}
+	return alerts, nil
+}
+
+// Список подписок пользователя
+func watchListMessage(lang string, usr usrData) string {
+	if len(usr.Watch) == 0 {
+		return tr(lang, "watch_empty")
+	}
+	retTxt := tr(lang, "watch_count", len(usr.Watch), WatchMax)
+	for _, oneWatch := range usr.Watch {
+		cnd, _ := allCand.Get(oneWatch.PubKey)
+		retTxt += "\n\n" + tr(lang, "watch_item",
+			oneWatch.PubKey,
+			getNodeStatusString(lang, cnd),
+			strings.Join(oneWatch.Alerts, ","))
+	}
+	return retTxt
+}
+
+// Проверка мастерноды из подписки, возвращает текст оповещения ("" - оповещать не нужно).
+// Состояние оповещений общее с мастернодами пользователя, поэтому свою мастерноду подпиской не отслеживаем
+func checkWatchAlert(chatID int64, lang string, oneWatch watchData, changes map[string]nodeChange, now time.Time) string {
+	oneNode := nodeData{
+		Label:        getMinString(oneWatch.PubKey),
+		PubKey:       oneWatch.PubKey,
+		Notification: hasWatchAlert(oneWatch, "down"),
+	}
+	alrtTxt := []string{}
+	if txt := checkNodeAlert(chatID, lang, oneNode, getStatusValid(oneWatch.PubKey), now); txt != "" {
+		alrtTxt = append(alrtTxt, txt)
+	}
+	oneNode.Notification = hasWatchAlert(oneWatch, "missed")
+	if txt := checkMissedAlert(chatID, lang, oneNode); txt != "" {
+		alrtTxt = append(alrtTxt, txt)
+	}
+	if chng, ok := changes[oneWatch.PubKey]; ok {
+		chng.Stake = chng.Stake && hasWatchAlert(oneWatch, "stake")
+		chng.Comm = chng.Comm && hasWatchAlert(oneWatch, "commission")
+		if txt := getChangeString(lang, oneNode.Label, chng); txt != "" {
+			alrtTxt = append(alrtTxt, txt)
+		}
+	}
+	return strings.Join(alrtTxt, "\n")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Настройки автоотключения мастерноды (секция [monitor] INI файла)
+var (
+	AutoOffMissed   = 12   // Отключать мастерноду, пропустившую столько блоков из окна
+	AutoOffCooldown = 3600 // Не отключать повторно раньше, чем через столько сек.
+)
+
+// Проверка настроек автоотключения: порог больше окна никогда не сработает
+func checkAutoOffConf() error {
+	if MissedWindow <= 0 {
+		return fmt.Errorf("MISSED_WINDOW должно быть больше 0: %d", MissedWindow)
+	}
+	if AutoOffMissed <= 0 || AutoOffMissed > MissedWindow {
+		return fmt.Errorf("AUTOOFF_MISSED должно быть от 1 до MISSED_WINDOW (%d): %d", MissedWindow, AutoOffMissed)
+	}
+	return nil
+}
+
+// Автоматическое отключение мастерноды, пропускающей блоки, возвращает текст оповещения ("" - оповещать не нужно).
+// Об отключении сообщаем, только когда транзакция попала в блок и статус мастерноды изменился
+func checkAutoOff(bot *tgbotapi.BotAPI, store UserStore, chatID int64, lang string, oneNode nodeData, now time.Time) string {
+	if !oneNode.AutoOff || oneNode.PrivKey == "" || AutoOffMissed <= 0 {
+		return ""
+	}
+	// ещё не прошла пауза после прошлого отключения
+	if now.Sub(oneNode.AutoOffAt) < time.Duration(AutoOffCooldown)*time.Second {
+		return ""
+	}
+	// мастернода уже не в валидаторах - отключать нечего
+	if !getStatusValid(oneNode.PubKey) {
+		return ""
+	}
+	missed, wnd := getMissedBlocks(oneNode.PubKey)
+	if missed < AutoOffMissed {
+		return ""
+	}
+
+	fmt.Println("AUTOOFF", chatID, oneNode.Label, missed)
+	tx, err := SetCandidateTransaction(oneNode.UserAddress, oneNode.PrivKey, oneNode.PubKey, false)
+	// пауза отсчитывается и после ошибки, чтобы не посылать транзакции каждый цикл
+	editNodeAutoOffTx(store, chatID, oneNode.PubKey, tx, now)
+	if err != nil {
+		return tr(lang, "autooff_failed", oneNode.Label, missed, wnd, err.Error())
+	}
+	label, pubKey := oneNode.Label, oneNode.PubKey
+	go trackTx(bot, chatID, lang, tx, func() string {
+		statusTxt, ok := checkCandidateStatus(lang, pubKey, false)
+		if !ok {
+			return tr(lang, "autooff_unconfirmed", label) + "\n" + statusTxt
+		}
+		return tr(lang, "autooff_done", label) + "\n" + statusTxt
+	})
+	return tr(lang, "autooff_sent", oneNode.Label, missed, wnd, tx)
+}
+
+// Сохранение транзакции автоотключения мастерноды в БД и в память. Мастерноду ищем
+// по паблик-кею: пока шла транзакция, пользователь мог изменить список мастернод
+func editNodeAutoOffTx(store UserStore, chatID int64, pubKey string, tx string, now time.Time) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode := findNode(*usr, pubKey); idxNode != -1 {
+			usr.Nodes[idxNode].AutoOffTx = tx
+			usr.Nodes[idxNode].AutoOffAt = now
+		}
+	})
+}
+
+// Вкл/откл автоотключения мастерноды в БД и в память
+func editNodeAutoOff(store UserStore, chatID int64, idxNode int, status bool) {
+	editUser(store, chatID, func(usr *usrData) {
+		if idxNode < len(usr.Nodes) {
+			usr.Nodes[idxNode].AutoOff = status
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCheckAutoOffConf(t *testing.T) {
+	oldWindow, oldMissed := MissedWindow, AutoOffMissed
+	defer func() { MissedWindow, AutoOffMissed = oldWindow, oldMissed }()
+
+	tests := []struct {
+		window  int
+		missed  int
+		wantErr bool
+	}{
+		{24, 12, false},
+		{24, 24, false},
+		{24, 1, false},
+		{24, 25, true},
+		{24, 0, true},
+		{24, -1, true},
+		{0, 0, true},
+	}
+	for _, tt := range tests {
+		MissedWindow, AutoOffMissed = tt.window, tt.missed
+		if err := checkAutoOffConf(); (err != nil) != tt.wantErr {
+			t.Errorf("checkAutoOffConf() window %d, missed %d = %v, want error %v", tt.window, tt.missed, err, tt.wantErr)
+		}
+	}
+}
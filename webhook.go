@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Настройки получения сообщений (секция [telegram] INI файла)
+var (
+	TgMode       string // polling (по умолчанию) или webhook
+	TgWebhookURL string // Публичный адрес вебхука, который регистрируется в Telegram
+	TgListen     string // Адрес на котором слушать вебхук, например :8443
+	TgCertFile   string // Сертификат и ключ для HTTPS, если пусто - простой HTTP (за reverse proxy)
+	TgKeyFile    string
+	TgSecret     string // Секретный токен, который Telegram передаёт в заголовке вебхука
+	TgAPIURL     string // Адрес API Telegram (для проверки на локальном сервере), пусто - api.telegram.org
+)
+
+// Заголовок с секретным токеном вебхука
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Подключение к боту, с заменой адреса API Telegram если он задан
+func newBotAPI(token string) (*tgbotapi.BotAPI, error) {
+	if TgAPIURL == "" {
+		return tgbotapi.NewBotAPI(token)
+	}
+	apiURL, err := url.Parse(TgAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: &apiURLTransport{apiURL: apiURL}}
+	return tgbotapi.NewBotAPIWithClient(token, client)
+}
+
+// Перенаправление запросов к api.telegram.org на другой адрес
+type apiURLTransport struct {
+	apiURL *url.URL
+}
+
+func (t *apiURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req2 := new(http.Request)
+	*req2 = *req
+	u := *req.URL
+	u.Scheme = t.apiURL.Scheme
+	u.Host = t.apiURL.Host
+	req2.URL = &u
+	req2.Host = ""
+	return http.DefaultTransport.RoundTrip(req2)
+}
+
+// Регистрация вебхука в Telegram и запуск HTTP(S) сервера, сообщения приходят в канал
+func startWebhook(bot *tgbotapi.BotAPI) (tgbotapi.UpdatesChannel, error) {
+	if TgWebhookURL == "" || TgListen == "" {
+		return nil, fmt.Errorf("для режима webhook нужно указать WEBHOOK_URL и LISTEN")
+	}
+	// без секрета любой, кто достучится до LISTEN, сможет подделать команды пользователей
+	if TgSecret == "" {
+		return nil, fmt.Errorf("для режима webhook нужно указать SECRET")
+	}
+	hookURL, err := url.Parse(TgWebhookURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setWebhook(bot)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan tgbotapi.Update, bot.Buffer)
+	path := hookURL.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, webhookHandler(TgSecret, updates))
+	srv := &http.Server{
+		Addr:         TgListen,
+		Handler:      mux,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		var err error
+		if TgCertFile != "" {
+			fmt.Printf("Вебхук: https://%s%s\n", TgListen, path)
+			err = srv.ListenAndServeTLS(TgCertFile, TgKeyFile)
+		} else {
+			fmt.Printf("Вебхук: http://%s%s\n", TgListen, path)
+			err = srv.ListenAndServe()
+		}
+		fmt.Println("Ошибка сервера вебхука:", err)
+		close(updates)
+	}()
+
+	return updates, nil
+}
+
+// Регистрация вебхука в Telegram. secret_token нет в WebhookConfig tgbotapi, поэтому
+// запрос собираем сами. Если указан CERT_FILE, сертификат загружается вместе с адресом -
+// без этого Telegram не примет самоподписанный сертификат
+func setWebhook(bot *tgbotapi.BotAPI) error {
+	params := map[string]string{"url": TgWebhookURL, "secret_token": TgSecret}
+	if TgCertFile != "" {
+		_, err := bot.UploadFile("setWebhook", params, "certificate", TgCertFile)
+		return err
+	}
+	values := url.Values{}
+	for key, val := range params {
+		values.Set(key, val)
+	}
+	_, err := bot.MakeRequest("setWebhook", values)
+	return err
+}
+
+// Обработчик запросов Telegram к вебхуку (при пустом secret отклоняются все запросы)
+func webhookHandler(secret string, updates chan<- tgbotapi.Update) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(secret)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&update)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		updates <- update
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Поддельный API Telegram: отвечает на getMe и запоминает параметры setWebhook
+func newFakeTelegram(t *testing.T) (*tgbotapi.BotAPI, map[string]string) {
+	hookParams := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"first_name":"tbot","username":"tbot"}}`))
+		case strings.HasSuffix(r.URL.Path, "/setWebhook"):
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				r.ParseForm()
+			}
+			for key, _ := range r.Form {
+				hookParams[key] = r.Form.Get(key)
+			}
+			if r.MultipartForm != nil {
+				for key, _ := range r.MultipartForm.File {
+					hookParams[key] = "<file>"
+				}
+			}
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	oldURL := TgAPIURL
+	TgAPIURL = srv.URL
+	t.Cleanup(func() { TgAPIURL = oldURL })
+	bot, err := newBotAPI("123:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot, hookParams
+}
+
+// Изменение настроек вебхука на время теста
+func setWebhookConf(t *testing.T, hookURL string, secret string, certFile string) {
+	oldURL, oldSecret, oldCert := TgWebhookURL, TgSecret, TgCertFile
+	TgWebhookURL, TgSecret, TgCertFile = hookURL, secret, certFile
+	t.Cleanup(func() { TgWebhookURL, TgSecret, TgCertFile = oldURL, oldSecret, oldCert })
+}
+
+func TestSetWebhook(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	ioutil.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----\n"), 0600)
+
+	tests := []struct {
+		name     string
+		certFile string
+		wantCert bool
+	}{
+		{"without certificate", "", false},
+		{"self-signed certificate", certFile, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, hookParams := newFakeTelegram(t)
+			setWebhookConf(t, "https://bot.example.com/tbot", "s3cret", tt.certFile)
+			if err := setWebhook(bot); err != nil {
+				t.Fatal(err)
+			}
+			if hookParams["url"] != "https://bot.example.com/tbot" {
+				t.Errorf("url = %q", hookParams["url"])
+			}
+			if hookParams["secret_token"] != "s3cret" {
+				t.Errorf("secret_token = %q, want s3cret", hookParams["secret_token"])
+			}
+			if _, ok := hookParams["certificate"]; ok != tt.wantCert {
+				t.Errorf("certificate uploaded = %v, want %v", ok, tt.wantCert)
+			}
+		})
+	}
+}
+
+func TestStartWebhookNoSecret(t *testing.T) {
+	bot, hookParams := newFakeTelegram(t)
+	setWebhookConf(t, "https://bot.example.com/tbot", "", "")
+	oldListen := TgListen
+	TgListen = "127.0.0.1:0"
+	t.Cleanup(func() { TgListen = oldListen })
+
+	if _, err := startWebhook(bot); err == nil {
+		t.Fatal("startWebhook() without SECRET: want error")
+	}
+	if len(hookParams) != 0 {
+		t.Fatalf("webhook registered without SECRET: %v", hookParams)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	body := `{"update_id":7,"message":{"message_id":1,"chat":{"id":42},"text":"/help"}}`
+	tests := []struct {
+		name       string
+		method     string
+		confSecret string // SECRET из настроек
+		secret     string // "-" - заголовка нет
+		body       string
+		wantCode   int
+	}{
+		{"valid update", http.MethodPost, "s3cret", "s3cret", body, http.StatusOK},
+		{"missing secret", http.MethodPost, "s3cret", "-", body, http.StatusForbidden},
+		{"empty secret", http.MethodPost, "s3cret", "", body, http.StatusForbidden},
+		{"wrong secret", http.MethodPost, "s3cret", "wrong", body, http.StatusForbidden},
+		{"no secret configured, missing header", http.MethodPost, "", "-", body, http.StatusForbidden},
+		{"no secret configured, empty header", http.MethodPost, "", "", body, http.StatusForbidden},
+		{"not json", http.MethodPost, "s3cret", "s3cret", "{", http.StatusBadRequest},
+		{"get", http.MethodGet, "s3cret", "s3cret", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := make(chan tgbotapi.Update, 1)
+			req := httptest.NewRequest(tt.method, "/tbot", strings.NewReader(tt.body))
+			if tt.secret != "-" {
+				req.Header.Set(webhookSecretHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			webhookHandler(tt.confSecret, updates).ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			select {
+			case update := <-updates:
+				if tt.wantCode != http.StatusOK {
+					t.Fatalf("update %d dispatched on rejected request", update.UpdateID)
+				}
+				if update.UpdateID != 7 || update.Message == nil || update.Message.Chat.ID != 42 {
+					t.Fatalf("update = %+v", update)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantCode == http.StatusOK {
+					t.Fatal("update not dispatched")
+				}
+			}
+		})
+	}
+}